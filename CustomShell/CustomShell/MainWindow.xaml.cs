@@ -11,6 +11,8 @@ using System.Windows.Threading;
 using System.Windows.Media.Imaging;
 using System.Windows.Media;
 using LanguageManager = CustomShell.controls.LanguageManager;
+using AgentClient = CustomShell.controls.AgentClient;
+using WarningWindow = CustomShell.controls.WarningWindow;
 
 namespace CustomShell
 {
@@ -21,6 +23,7 @@ namespace CustomShell
         private TasksService _tasksService;
         private bool _isShellInitialized = false;
         private IntPtr _activeWindowHandle = IntPtr.Zero;
+        private AgentClient _agentClient;
 
         public MainWindow()
         {
@@ -61,6 +64,7 @@ namespace CustomShell
             HideSystemTaskbar();
             PositionAndRegisterBar();
             InitializeShell();
+            InitializeAgentClient();
 
             // Таймер для отслеживания активного окна
             var activeWindowTimer = new DispatcherTimer
@@ -74,6 +78,15 @@ namespace CustomShell
             this.Activate();
         }
 
+        // Предупреждения агента (запрещенные программы, дневные лимиты)
+        private void InitializeAgentClient()
+        {
+            _agentClient = new AgentClient();
+            _agentClient.WarningReceived += msg =>
+                Dispatcher.InvokeAsync(() => new WarningWindow(msg).Show());
+            _agentClient.Start();
+        }
+
         private void UpdateActiveWindow()
         {
             IntPtr foreground = GetForegroundWindow();
@@ -613,6 +626,7 @@ namespace CustomShell
             }
 
             _tasksService?.Dispose();
+            _agentClient?.Dispose();
 
             ShowSystemTaskbar();
 
@@ -0,0 +1,133 @@
+﻿using System;
+using System.IO;
+using System.IO.Pipes;
+using System.Text;
+using System.Text.Json;
+using System.Text.Json.Serialization;
+using System.Threading;
+using System.Threading.Tasks;
+
+namespace CustomShell.controls
+{
+    // Сообщение канала агента (models.IPCMessage в School_agent)
+    public class AgentMessage
+    {
+        [JsonPropertyName("cmd")]
+        public string Command { get; set; }
+
+        [JsonPropertyName("user")]
+        public string User { get; set; }
+
+        [JsonPropertyName("program")]
+        public string Program { get; set; }
+
+        [JsonPropertyName("message")]
+        public string Message { get; set; }
+
+        // Секунды до завершения программы; 0 — только предупреждение
+        [JsonPropertyName("countdown")]
+        public int Countdown { get; set; }
+    }
+
+    // Подписка на уведомления агента. После {"cmd":"subscribe"} агент держит
+    // канал открытым и присылает JSON-сообщения, по одному на строку.
+    // При обрыве (агент перезапускается) клиент переподключается.
+    public class AgentClient : IDisposable
+    {
+        private const string PipeName = "SchoolAgentIPC";
+        private const string CmdSubscribe = "subscribe";
+        private const string CmdWarning = "warning";
+
+        private static readonly TimeSpan RetryDelay = TimeSpan.FromSeconds(5);
+        private static readonly JsonSerializerOptions JsonOptions = new JsonSerializerOptions
+        {
+            DefaultIgnoreCondition = JsonIgnoreCondition.WhenWritingDefault
+        };
+
+        private readonly CancellationTokenSource _cts = new CancellationTokenSource();
+        private NamedPipeClientStream _pipe;
+
+        // Вызывается из фонового потока
+        public event Action<AgentMessage> WarningReceived;
+
+        public void Start()
+        {
+            Task.Run(() => RunAsync(_cts.Token));
+        }
+
+        private async Task RunAsync(CancellationToken token)
+        {
+            while (!token.IsCancellationRequested)
+            {
+                try
+                {
+                    using (var pipe = new NamedPipeClientStream(".", PipeName, PipeDirection.InOut, PipeOptions.Asynchronous))
+                    {
+                        await pipe.ConnectAsync(5000, token);
+                        _pipe = pipe;
+
+                        var writer = new StreamWriter(pipe, new UTF8Encoding(false)) { AutoFlush = true };
+                        await writer.WriteLineAsync(JsonSerializer.Serialize(new AgentMessage { Command = CmdSubscribe }, JsonOptions));
+
+                        var reader = new StreamReader(pipe, Encoding.UTF8);
+                        string line;
+                        while ((line = await reader.ReadLineAsync()) != null)
+                        {
+                            HandleLine(line);
+                        }
+                    }
+                }
+                catch (OperationCanceledException)
+                {
+                    return;
+                }
+                catch (Exception)
+                {
+                    // Агент не запущен или канал закрыт — пробуем позже
+                }
+                finally
+                {
+                    _pipe = null;
+                }
+
+                try
+                {
+                    await Task.Delay(RetryDelay, token);
+                }
+                catch (OperationCanceledException)
+                {
+                    return;
+                }
+            }
+        }
+
+        private void HandleLine(string line)
+        {
+            AgentMessage msg;
+            try
+            {
+                msg = JsonSerializer.Deserialize<AgentMessage>(line);
+            }
+            catch (JsonException)
+            {
+                return;
+            }
+
+            if (msg == null || msg.Command != CmdWarning)
+                return;
+
+            // Агент рассылает всем оболочкам; показываем только свои предупреждения
+            if (!string.IsNullOrEmpty(msg.User) &&
+                !string.Equals(msg.User, Environment.UserName, StringComparison.OrdinalIgnoreCase))
+                return;
+
+            WarningReceived?.Invoke(msg);
+        }
+
+        public void Dispose()
+        {
+            _cts.Cancel();
+            _pipe?.Dispose();
+        }
+    }
+}
@@ -0,0 +1,81 @@
+﻿using System;
+using System.Windows;
+using System.Windows.Controls;
+using System.Windows.Threading;
+
+namespace CustomShell.controls
+{
+    // Предупреждение агента: запрещенная программа или исчерпанный лимит.
+    // С отсчетом окно показывает, через сколько секунд программа будет закрыта.
+    public class WarningWindow : Window
+    {
+        private readonly TextBlock _countdownText;
+        private readonly DispatcherTimer _timer;
+        private int _secondsLeft;
+
+        public WarningWindow(AgentMessage msg)
+        {
+            Title = string.IsNullOrEmpty(msg.Program) ? "Предупреждение" : msg.Program;
+            Topmost = true;
+            ShowActivated = true;
+            ResizeMode = ResizeMode.NoResize;
+            WindowStyle = WindowStyle.ToolWindow;
+            WindowStartupLocation = WindowStartupLocation.CenterScreen;
+            SizeToContent = SizeToContent.WidthAndHeight;
+            MaxWidth = 480;
+
+            var panel = new StackPanel { Margin = new Thickness(20) };
+            panel.Children.Add(new TextBlock
+            {
+                Text = msg.Message,
+                TextWrapping = TextWrapping.Wrap,
+                FontSize = 15
+            });
+
+            _countdownText = new TextBlock { Margin = new Thickness(0, 10, 0, 0), FontSize = 13 };
+            panel.Children.Add(_countdownText);
+
+            var okButton = new Button
+            {
+                Content = "OK",
+                Width = 90,
+                Margin = new Thickness(0, 16, 0, 0),
+                HorizontalAlignment = HorizontalAlignment.Right,
+                IsDefault = true
+            };
+            okButton.Click += (s, e) => Close();
+            panel.Children.Add(okButton);
+
+            Content = panel;
+
+            _secondsLeft = msg.Countdown;
+            if (_secondsLeft > 0)
+            {
+                UpdateCountdown();
+                _timer = new DispatcherTimer { Interval = TimeSpan.FromSeconds(1) };
+                _timer.Tick += (s, e) =>
+                {
+                    _secondsLeft--;
+                    if (_secondsLeft <= 0)
+                    {
+                        Close();
+                        return;
+                    }
+                    UpdateCountdown();
+                };
+                _timer.Start();
+            }
+            else
+            {
+                _countdownText.Visibility = Visibility.Collapsed;
+            }
+
+            Closed += (s, e) => _timer?.Stop();
+        }
+
+        private void UpdateCountdown()
+        {
+            _countdownText.Text = $"Программа будет закрыта через {_secondsLeft} с";
+        }
+    }
+}
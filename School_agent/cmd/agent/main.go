@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"school_agent/internal/sysuser" 
+	"school_agent/internal/userhelper"
 	"school_agent/internal/winsvc"
 	"time"
 
@@ -11,6 +12,12 @@ import (
 )
 
 func main() {
+	// Помощник фокуса: служба запускает его в сессии пользователя
+	if len(os.Args) > 1 && os.Args[1] == userhelper.Arg {
+		userhelper.Run(os.Args[2:])
+		return
+	}
+
 	// 1. Логгер сервиса (service.log)
 	logFile, err := os.OpenFile("C:\\ProgramData\\SchoolAgent\\service.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err == nil {
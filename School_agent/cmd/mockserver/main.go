@@ -0,0 +1,115 @@
+// mockserver — эталонный сервер протокола агента для локальной разработки
+// и интеграционных тестов.
+//
+//	mockserver -addr :8080 -data ./mockdata            запуск сервера
+//	mockserver send -device PC-01 -type UPLOAD_LOGS     отправка команды агенту
+//	mockserver faults -drop 0.2 -ack-delay 5s           настройка сбоев
+//	mockserver devices                                  список подключенных агентов
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "send":
+			cmdSend(os.Args[2:])
+			return
+		case "faults":
+			cmdFaults(os.Args[2:])
+			return
+		case "devices":
+			cmdDevices(os.Args[2:])
+			return
+		}
+	}
+
+	addr := flag.String("addr", ":8080", "listen address")
+	dataDir := flag.String("data", "mockdata", "directory for recorded messages")
+	version := flag.Int("protocol", 2, "highest protocol version the server accepts")
+	token := flag.String("token", "", "required device token (empty accepts any)")
+	flag.Parse()
+
+	srv, err := newServer(*dataDir, *version, *token)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("Mock server listening on %s, recording to %s", *addr, *dataDir)
+	log.Fatal(http.ListenAndServe(*addr, srv.routes()))
+}
+
+func cmdSend(args []string) {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	admin := fs.String("admin", "http://localhost:8080", "mock server address")
+	device := fs.String("device", "", "target device (empty sends to all)")
+	cmdType := fs.String("type", "", "command type, e.g. UPLOAD_LOGS")
+	raw := fs.String("json", "", "full command JSON instead of -type")
+	fs.Parse(args)
+
+	body := []byte(*raw)
+	if len(body) == 0 {
+		if *cmdType == "" {
+			log.Fatal("either -type or -json is required")
+		}
+		body, _ = json.Marshal(map[string]string{"type": *cmdType})
+	}
+
+	q := url.Values{}
+	q.Set("device", *device)
+	adminPost(*admin+"/admin/command?"+q.Encode(), body)
+}
+
+func cmdFaults(args []string) {
+	fs := flag.NewFlagSet("faults", flag.ExitOnError)
+	admin := fs.String("admin", "http://localhost:8080", "mock server address")
+	var f faults
+	fs.Float64Var(&f.DropRate, "drop", 0, "probability of closing the connection on an incoming message")
+	fs.DurationVar(&f.AckDelay, "ack-delay", 0, "delay before LOGS_ACK")
+	fs.Float64Var(&f.RejectRate, "reject", 0, "probability of rejecting a log chunk")
+	fs.BoolVar(&f.RejectAuth, "reject-auth", false, "refuse all new connections")
+	fs.BoolVar(&f.BlockWebSocket, "block-ws", false, "fail WebSocket upgrades to force the HTTPS fallback")
+	fs.Parse(args)
+
+	body, _ := json.Marshal(f)
+	adminPost(*admin+"/admin/faults", body)
+}
+
+func cmdDevices(args []string) {
+	fs := flag.NewFlagSet("devices", flag.ExitOnError)
+	admin := fs.String("admin", "http://localhost:8080", "mock server address")
+	fs.Parse(args)
+
+	resp, err := http.Get(*admin + "/admin/devices")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(os.Stdout, resp.Body)
+}
+
+func adminPost(u string, body []byte) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(u, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		log.Fatalf("%s: %s", resp.Status, out)
+	}
+	fmt.Print(string(out))
+}
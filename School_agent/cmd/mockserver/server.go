@@ -0,0 +1,521 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"school_agent/internal/protocol"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// serverCapabilities — возможности, которые сервер подтверждает агенту
+var serverCapabilities = []string{protocol.CapLogMetadata, protocol.CapLogChunks}
+
+// faults — искусственные сбои, настраиваются через /admin/faults
+type faults struct {
+	DropRate       float64       `json:"drop_rate"`
+	AckDelay       time.Duration `json:"ack_delay"`
+	RejectRate     float64       `json:"reject_rate"`
+	RejectAuth     bool          `json:"reject_auth"`
+	BlockWebSocket bool          `json:"block_ws"`
+}
+
+// message — общие поля всех сообщений агента
+type message struct {
+	Type     string `json:"type"`
+	Token    string `json:"token"`
+	Device   string `json:"device"`
+	Protocol struct {
+		Versions     []int    `json:"versions"`
+		Capabilities []string `json:"capabilities"`
+	} `json:"protocol"`
+
+	// logs / logs_chunk
+	Data     json.RawMessage `json:"data"`
+	BatchID  string          `json:"batch_id"`
+	Seq      int             `json:"seq"`
+	Encoding string          `json:"encoding"`
+}
+
+type device struct {
+	Name      string    `json:"name"`
+	Transport string    `json:"transport"`
+	Version   int       `json:"version"`
+	LastSeen  time.Time `json:"last_seen"`
+	Messages  int       `json:"messages"`
+	Logs      int       `json:"logs"`
+
+	wsMu   sync.Mutex
+	ws     *websocket.Conn
+	outbox chan []byte
+}
+
+type server struct {
+	dataDir    string
+	maxVersion int
+	token      string
+
+	mu      sync.Mutex
+	devices map[string]*device
+	faults  faults
+
+	upgrader websocket.Upgrader
+}
+
+var (
+	errDropped    = errors.New("connection dropped by fault injection")
+	errDeviceName = errors.New("bad device name")
+)
+
+func newServer(dataDir string, maxVersion int, token string) (*server, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	return &server{
+		dataDir:    dataDir,
+		maxVersion: maxVersion,
+		token:      token,
+		devices:    make(map[string]*device),
+		upgrader:   websocket.Upgrader{EnableCompression: true},
+	}, nil
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleWS)
+	mux.HandleFunc("/agent/messages", s.handleHTTPMessages)
+	mux.HandleFunc("/agent/commands", s.handleHTTPCommands)
+	mux.HandleFunc("/admin/command", s.handleAdminCommand)
+	mux.HandleFunc("/admin/faults", s.handleAdminFaults)
+	mux.HandleFunc("/admin/devices", s.handleAdminDevices)
+	return mux
+}
+
+func (s *server) currentFaults() faults {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.faults
+}
+
+// device возвращает устройство по имени из сообщения агента. Имя становится
+// каталогом в dataDir, поэтому пути и ".." не принимаются.
+func (s *server) device(name, transport string) (*device, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return nil, errDeviceName
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.devices[name]
+	if !ok {
+		d = &device{Name: name, outbox: make(chan []byte, 100)}
+		s.devices[name] = d
+	}
+	d.Transport = transport
+	d.LastSeen = time.Now()
+	return d, nil
+}
+
+// --- WebSocket ---
+
+func (s *server) handleWS(w http.ResponseWriter, r *http.Request) {
+	if s.currentFaults().BlockWebSocket {
+		http.Error(w, "websocket blocked", http.StatusForbidden)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, first, err := conn.ReadMessage()
+	if err != nil {
+		return
+	}
+	var auth message
+	if json.Unmarshal(first, &auth) != nil || auth.Type != "auth" {
+		log.Printf("WS: first message is not auth")
+		return
+	}
+
+	name := auth.Device
+	if name == "" {
+		name = auth.Token
+	}
+	d, err := s.device(name, "websocket")
+	if err != nil {
+		log.Printf("WS %q: %v", name, err)
+		return
+	}
+	d.wsMu.Lock()
+	d.ws = conn
+	d.wsMu.Unlock()
+	defer func() {
+		d.wsMu.Lock()
+		if d.ws == conn {
+			d.ws = nil
+		}
+		d.wsMu.Unlock()
+	}()
+
+	if err := s.handleMessage(d, first); err != nil {
+		log.Printf("WS %s: %v", name, err)
+		return
+	}
+	log.Printf("WS %s connected", name)
+
+	// Команды, накопленные пока агент был офлайн
+	go s.flushOutbox(d, conn)
+
+	for {
+		_, raw, err := conn.ReadMessage()
+		if err != nil {
+			log.Printf("WS %s disconnected: %v", name, err)
+			return
+		}
+		if err := s.handleMessage(d, raw); err != nil {
+			log.Printf("WS %s: %v", name, err)
+			return
+		}
+	}
+}
+
+func (s *server) flushOutbox(d *device, conn *websocket.Conn) {
+	for {
+		select {
+		case cmd := <-d.outbox:
+			d.wsMu.Lock()
+			if d.ws != conn {
+				d.wsMu.Unlock()
+				d.outbox <- cmd
+				return
+			}
+			conn.WriteMessage(websocket.TextMessage, cmd)
+			d.wsMu.Unlock()
+		case <-time.After(time.Minute):
+			d.wsMu.Lock()
+			alive := d.ws == conn
+			d.wsMu.Unlock()
+			if !alive {
+				return
+			}
+		}
+	}
+}
+
+// --- HTTPS fallback ---
+
+func (s *server) handleHTTPMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var batch struct {
+		Device   string            `json:"device"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := s.device(batch.Device, "https")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, raw := range batch.Messages {
+		if err := s.handleMessage(d, raw); err != nil {
+			status := http.StatusServiceUnavailable
+			if !errors.Is(err, errDropped) {
+				status = http.StatusUnauthorized
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) handleHTTPCommands(w http.ResponseWriter, r *http.Request) {
+	d, err := s.device(r.URL.Query().Get("device"), "https")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wait := 30 * time.Second
+	if v, err := time.ParseDuration(r.URL.Query().Get("wait") + "s"); err == nil && v < wait {
+		wait = v
+	}
+
+	var cmds []json.RawMessage
+	select {
+	case cmd := <-d.outbox:
+		cmds = append(cmds, cmd)
+	case <-time.After(wait):
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case <-r.Context().Done():
+		return
+	}
+
+	for more := true; more; {
+		select {
+		case cmd := <-d.outbox:
+			cmds = append(cmds, cmd)
+		default:
+			more = false
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cmds)
+}
+
+// --- Протокол ---
+
+func (s *server) handleMessage(d *device, raw []byte) error {
+	f := s.currentFaults()
+	s.record(d, "messages.jsonl", raw)
+
+	s.mu.Lock()
+	d.Messages++
+	d.LastSeen = time.Now()
+	s.mu.Unlock()
+
+	if f.DropRate > 0 && rand.Float64() < f.DropRate {
+		return errDropped
+	}
+
+	var msg message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		log.Printf("%s: bad message: %v", d.Name, err)
+		return nil
+	}
+
+	switch msg.Type {
+	case "auth":
+		if f.RejectAuth || (s.token != "" && msg.Token != s.token) {
+			return errors.New("auth rejected")
+		}
+		version := protocol.Negotiate(s.versions(), msg.Protocol.Versions)
+		s.mu.Lock()
+		d.Version = version
+		s.mu.Unlock()
+		if version > protocol.V1 {
+			s.send(d, map[string]interface{}{
+				"type":         "PROTOCOL",
+				"version":      version,
+				"capabilities": intersect(serverCapabilities, msg.Protocol.Capabilities),
+			})
+		}
+
+	case "logs":
+		s.recordLogs(d, msg.Data)
+
+	case "logs_chunk":
+		go s.ackChunk(d, msg, f)
+	}
+	return nil
+}
+
+// versions — версии протокола, которые принимает сервер, по убыванию
+func (s *server) versions() []int {
+	var out []int
+	for _, v := range protocol.Supported {
+		if v <= s.maxVersion {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (s *server) ackChunk(d *device, msg message, f faults) {
+	if f.AckDelay > 0 {
+		time.Sleep(f.AckDelay)
+	}
+
+	ack := map[string]interface{}{"type": "LOGS_ACK", "batch_id": msg.BatchID, "seq": msg.Seq}
+	if f.RejectRate > 0 && rand.Float64() < f.RejectRate {
+		ack["error"] = "rejected by fault injection"
+		s.send(d, ack)
+		return
+	}
+
+	data, err := decodeChunk(msg)
+	if err != nil {
+		ack["error"] = err.Error()
+		s.send(d, ack)
+		return
+	}
+	s.recordLogs(d, data)
+	s.send(d, ack)
+}
+
+func decodeChunk(msg message) (json.RawMessage, error) {
+	if msg.Encoding != "gzip" {
+		return msg.Data, nil
+	}
+	var b64 string
+	if err := json.Unmarshal(msg.Data, &b64); err != nil {
+		return nil, err
+	}
+	compressed, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
+
+// send доставляет команду агенту: сразу по WebSocket или через очередь long-polling
+func (s *server) send(d *device, cmd interface{}) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return
+	}
+
+	d.wsMu.Lock()
+	conn := d.ws
+	if conn != nil {
+		err = conn.WriteMessage(websocket.TextMessage, data)
+	}
+	d.wsMu.Unlock()
+
+	if conn == nil || err != nil {
+		select {
+		case d.outbox <- data:
+		default:
+			log.Printf("%s: command queue is full, dropping %s", d.Name, data)
+		}
+	}
+}
+
+// --- Запись на диск ---
+
+func (s *server) record(d *device, file string, raw []byte) {
+	s.mu.Lock()
+	transport := d.Transport
+	s.mu.Unlock()
+
+	dir := filepath.Join(s.dataDir, d.Name)
+	os.MkdirAll(dir, 0755)
+	f, err := os.OpenFile(filepath.Join(dir, file), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	line, _ := json.Marshal(map[string]interface{}{
+		"received_at": time.Now(),
+		"transport":   transport,
+		"message":     json.RawMessage(raw),
+	})
+	f.Write(line)
+	f.WriteString("\n")
+}
+
+func (s *server) recordLogs(d *device, data json.RawMessage) {
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	for _, e := range entries {
+		s.record(d, "logs.jsonl", e)
+	}
+	s.mu.Lock()
+	d.Logs += len(entries)
+	s.mu.Unlock()
+}
+
+// --- Admin API ---
+
+func (s *server) handleAdminCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var cmd map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil || cmd["type"] == nil {
+		http.Error(w, "command JSON with a type field is required", http.StatusBadRequest)
+		return
+	}
+
+	target := r.URL.Query().Get("device")
+	s.mu.Lock()
+	var targets []*device
+	for name, d := range s.devices {
+		if target == "" || target == name {
+			targets = append(targets, d)
+		}
+	}
+	s.mu.Unlock()
+
+	if len(targets) == 0 {
+		http.Error(w, "no such device", http.StatusNotFound)
+		return
+	}
+	for _, d := range targets {
+		s.send(d, cmd)
+	}
+	json.NewEncoder(w).Encode(map[string]int{"sent": len(targets)})
+}
+
+func (s *server) handleAdminFaults(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		var f faults
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.faults = f
+		s.mu.Unlock()
+		log.Printf("Faults set: %+v", f)
+	}
+	json.NewEncoder(w).Encode(s.currentFaults())
+}
+
+func (s *server) handleAdminDevices(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*device, 0, len(s.devices))
+	for _, d := range s.devices {
+		list = append(list, d)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func intersect(a, b []string) []string {
+	set := make(map[string]bool)
+	for _, v := range b {
+		set[v] = true
+	}
+	var out []string
+	for _, v := range a {
+		if set[v] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
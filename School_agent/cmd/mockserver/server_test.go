@@ -0,0 +1,118 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"school_agent/internal/config"
+	"school_agent/internal/protocol"
+	"school_agent/internal/ws"
+	"strings"
+	"testing"
+	"time"
+)
+
+func startServer(t *testing.T, dataDir string) *httptest.Server {
+	t.Helper()
+	s, err := newServer(dataDir, protocol.V2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s.routes())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// waitFor ждет условия, которое выполняется асинхронно
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for i := 0; !cond(); i++ {
+		if i == 500 {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Агент подключается к мок-серверу, согласует V2 и получает команду администратора
+func TestAgentClientAgainstMockServer(t *testing.T) {
+	dataDir := t.TempDir()
+	srv := startServer(t, dataDir)
+
+	cfg := &config.Config{
+		ServerURL: "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws",
+		Hostname:  "pc-01",
+		Transport: config.TransportWebSocket,
+	}
+	client := ws.New(cfg)
+	stop := make(chan struct{})
+	defer close(stop)
+	client.Start(stop)
+
+	select {
+	case <-client.ConnectedChan:
+	case <-time.After(5 * time.Second):
+		t.Fatal("client did not connect")
+	}
+	waitFor(t, "protocol negotiation", func() bool { return client.HasCapability(protocol.CapLogChunks) })
+
+	resp, err := http.Post(srv.URL+"/admin/command?device=pc-01", "application/json", strings.NewReader(`{"type":"GET_USER"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin command: %s", resp.Status)
+	}
+	select {
+	case cmd := <-client.CommandChan:
+		if cmd.Type != "GET_USER" {
+			t.Fatalf("command: %+v", cmd)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("command not delivered")
+	}
+
+	client.SendHeartbeat("pupil")
+	recorded := filepath.Join(dataDir, "pc-01", "messages.jsonl")
+	waitFor(t, "recorded heartbeat", func() bool {
+		data, _ := os.ReadFile(recorded)
+		return strings.Contains(string(data), `"pupil"`)
+	})
+	data, _ := os.ReadFile(recorded)
+	if !strings.Contains(string(data), `"transport":"websocket"`) {
+		t.Errorf("recorded messages: %s", data)
+	}
+}
+
+// Имя устройства становится каталогом: пути и ".." отклоняются
+func TestDeviceNameStaysInDataDir(t *testing.T) {
+	root := t.TempDir()
+	srv := startServer(t, filepath.Join(root, "data"))
+
+	for _, name := range []string{"../escape", "..", "a/b", ""} {
+		body := `{"device":"` + name + `","messages":[{"type":"heartbeat"}]}`
+		resp, err := http.Post(srv.URL+"/agent/messages", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("device %q: %s, want 400", name, resp.Status)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/agent/commands?wait=0&device=" + "..%2Fescape")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("commands for ../escape: %s, want 400", resp.Status)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "escape")); !os.IsNotExist(err) {
+		t.Errorf("message recorded outside the data dir: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package category
+
+import (
+	"log"
+	"net/url"
+	"school_agent/internal/models"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Database сопоставляет программы и сайты категориям: встроенный
+// справочник плюс переопределения из конфига или от сервера
+type Database struct {
+	mu      sync.RWMutex
+	version string
+	exes    map[string]string
+	hashes  map[string]string
+	domains map[string]string
+}
+
+func New() *Database {
+	d := &Database{}
+	d.Apply(models.Categories{})
+	return d
+}
+
+// Apply заменяет переопределения; встроенный справочник остается под ними
+func (d *Database) Apply(c models.Categories) {
+	exes := merge(defaultExes, c.Exes)
+	hashes := merge(nil, c.Hashes)
+	domains := make(map[string]string, len(defaultDomains)+len(c.Domains))
+	for k, v := range merge(defaultDomains, c.Domains) {
+		domains[strings.TrimPrefix(k, "www.")] = v
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version = c.Version
+	d.exes, d.hashes, d.domains = exes, hashes, domains
+
+	log.Printf("Categories %q applied: %d exes, %d hashes, %d domains overridden", c.Version, len(c.Exes), len(c.Hashes), len(c.Domains))
+}
+
+func merge(base, overrides map[string]string) map[string]string {
+	out := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		out[strings.ToLower(k)] = v
+	}
+	for k, v := range overrides {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+func (d *Database) Version() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.version
+}
+
+// ForApp — категория программы по имени exe
+func (d *Database) ForApp(name string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.exes[strings.ToLower(name)]
+}
+
+// ForProcess учитывает хеш и исходное имя файла, поэтому переименованная
+// программа попадает в свою настоящую категорию
+func (d *Database) ForProcess(name string, id *models.ExeIdentity) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if id != nil {
+		if c, ok := d.hashes[strings.ToLower(id.SHA256)]; ok && id.SHA256 != "" {
+			return c
+		}
+		if c, ok := d.exes[strings.ToLower(id.OriginalName)]; ok && id.OriginalName != "" {
+			return c
+		}
+	}
+	return d.exes[strings.ToLower(name)]
+}
+
+// ForURL ищет домен адреса, затем его родительские домены:
+// m.youtube.com -> youtube.com
+func (d *Database) ForURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for host != "" {
+		if c, ok := d.domains[host]; ok {
+			return c
+		}
+		idx := strings.Index(host, ".")
+		if idx == -1 {
+			break
+		}
+		host = host[idx+1:]
+	}
+	return ""
+}
+
+// Summarize проставляет категории приложениям сводки и суммирует время по категориям
+func (d *Database) Summarize(s *models.UsageSummary) {
+	byCategory := make(map[string]*models.CategoryUsage)
+	for i := range s.Apps {
+		app := &s.Apps[i]
+		app.Category = d.ForApp(app.App)
+		if app.Category == "" {
+			continue
+		}
+
+		c, ok := byCategory[app.Category]
+		if !ok {
+			c = &models.CategoryUsage{Category: app.Category}
+			byCategory[app.Category] = c
+		}
+		c.TotalSec += app.TotalSec
+		c.FocusSec += app.FocusSec
+		c.Launches += app.Launches
+		c.Apps = append(c.Apps, app.App)
+	}
+
+	s.Categories = s.Categories[:0]
+	for _, c := range byCategory {
+		s.Categories = append(s.Categories, *c)
+	}
+	sort.Slice(s.Categories, func(i, j int) bool { return s.Categories[i].TotalSec > s.Categories[j].TotalSec })
+}
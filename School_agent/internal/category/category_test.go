@@ -0,0 +1,106 @@
+package category
+
+import (
+	"school_agent/internal/models"
+	"testing"
+)
+
+func newTestDatabase() *Database {
+	d := New()
+	d.Apply(models.Categories{
+		Version: "1",
+		Exes:    map[string]string{"Steam.exe": models.CategoryEducation, "lab.exe": models.CategoryEducation},
+		Hashes:  map[string]string{"ABC123": models.CategoryGames},
+		Domains: map[string]string{"www.school.example": models.CategoryEducation, "youtube.com": models.CategoryEducation},
+	})
+	return d
+}
+
+// Хеш важнее исходного имени, исходное имя важнее имени процесса;
+// переопределения важнее встроенного справочника
+func TestForProcessPrecedence(t *testing.T) {
+	d := newTestDatabase()
+	tests := []struct {
+		desc string
+		name string
+		id   *models.ExeIdentity
+		want string
+	}{
+		{"built-in", "winword.exe", nil, models.CategoryOffice},
+		{"built-in, any case", "WinWord.EXE", nil, models.CategoryOffice},
+		{"override", "steam.exe", nil, models.CategoryEducation},
+		{"new entry", "lab.exe", nil, models.CategoryEducation},
+		{"unknown", "tool.exe", nil, ""},
+		{"original name of a renamed exe", "notes.exe", &models.ExeIdentity{OriginalName: "Minecraft.exe"}, models.CategoryGames},
+		{"hash before original name", "notes.exe", &models.ExeIdentity{SHA256: "abc123", OriginalName: "code.exe"}, models.CategoryGames},
+		{"unknown hash and original name", "code.exe", &models.ExeIdentity{SHA256: "ffff", OriginalName: "x.exe"}, models.CategoryProgramming},
+		{"empty identity", "code.exe", &models.ExeIdentity{}, models.CategoryProgramming},
+	}
+	for _, tt := range tests {
+		if got := d.ForProcess(tt.name, tt.id); got != tt.want {
+			t.Errorf("%s: ForProcess(%q) = %q, want %q", tt.desc, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForURL(t *testing.T) {
+	d := newTestDatabase()
+	tests := []struct {
+		url, want string
+	}{
+		{"https://vk.com/feed", models.CategorySocial},
+		{"https://m.vk.com/", models.CategorySocial},
+		{"https://docs.google.com/document/d/1", models.CategoryOffice},
+		{"https://www.google.com/search?q=x", models.CategorySearch},
+		{"https://www.youtube.com/watch?v=1", models.CategoryEducation},
+		{"https://school.example/lesson", models.CategoryEducation},
+		{"https://unknown.example/", ""},
+		{"not a url", ""},
+	}
+	for _, tt := range tests {
+		if got := d.ForURL(tt.url); got != tt.want {
+			t.Errorf("ForURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+// Новый набор заменяет прежние переопределения, встроенный справочник остается
+func TestApplyReplacesOverrides(t *testing.T) {
+	d := newTestDatabase()
+	d.Apply(models.Categories{Version: "2"})
+
+	if got := d.ForApp("steam.exe"); got != models.CategoryGames {
+		t.Errorf("steam.exe after reset = %q, want built-in %q", got, models.CategoryGames)
+	}
+	if got := d.ForApp("lab.exe"); got != "" {
+		t.Errorf("lab.exe after reset = %q, want no category", got)
+	}
+	if d.Version() != "2" {
+		t.Errorf("Version = %q", d.Version())
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	d := New()
+	s := models.UsageSummary{Apps: []models.AppUsage{
+		{App: "steam.exe", TotalSec: 100, Launches: 1},
+		{App: "minecraft.exe", TotalSec: 300, FocusSec: 200, Launches: 2},
+		{App: "winword.exe", TotalSec: 50},
+		{App: "tool.exe", TotalSec: 1000},
+	}}
+	d.Summarize(&s)
+
+	if s.Apps[3].Category != "" {
+		t.Errorf("unknown app got category %q", s.Apps[3].Category)
+	}
+	if len(s.Categories) != 2 {
+		t.Fatalf("categories: %+v", s.Categories)
+	}
+	games := s.Categories[0]
+	if games.Category != models.CategoryGames || games.TotalSec != 400 || games.FocusSec != 200 || games.Launches != 3 || len(games.Apps) != 2 {
+		t.Errorf("games: %+v", games)
+	}
+	if s.Categories[1].Category != models.CategoryOffice {
+		t.Errorf("second category: %+v", s.Categories[1])
+	}
+}
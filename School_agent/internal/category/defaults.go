@@ -0,0 +1,142 @@
+package category
+
+import "school_agent/internal/models"
+
+// Встроенный справочник: программы из списка отслеживания по умолчанию
+// и сайты, которые чаще всего встречаются в школьных отчетах
+var defaultExes = map[string]string{
+	"chrome.exe":  models.CategoryBrowser,
+	"msedge.exe":  models.CategoryBrowser,
+	"firefox.exe": models.CategoryBrowser,
+	"browser.exe": models.CategoryBrowser,
+	"opera.exe":   models.CategoryBrowser,
+
+	"winword.exe":   models.CategoryOffice,
+	"excel.exe":     models.CategoryOffice,
+	"powerpnt.exe":  models.CategoryOffice,
+	"onenote.exe":   models.CategoryOffice,
+	"acrord32.exe":  models.CategoryOffice,
+	"acrobat.exe":   models.CategoryOffice,
+	"notepad.exe":   models.CategoryOffice,
+	"notepad++.exe": models.CategoryOffice,
+	"soffice.exe":   models.CategoryOffice,
+
+	"code.exe":                          models.CategoryProgramming,
+	"devenv.exe":                        models.CategoryProgramming,
+	"visualstudio.exe":                  models.CategoryProgramming,
+	"python.exe":                        models.CategoryProgramming,
+	"pythonw.exe":                       models.CategoryProgramming,
+	"java.exe":                          models.CategoryProgramming,
+	"javaw.exe":                         models.CategoryProgramming,
+	"node.exe":                          models.CategoryProgramming,
+	"git.exe":                           models.CategoryProgramming,
+	"cmd.exe":                           models.CategoryProgramming,
+	"powershell.exe":                    models.CategoryProgramming,
+	"pycharm64.exe":                     models.CategoryProgramming,
+	"idea64.exe":                        models.CategoryProgramming,
+	"codeblocks.exe":                    models.CategoryProgramming,
+	"pascalabcnet.exe":                  models.CategoryProgramming,
+	"kumir.exe":                         models.CategoryProgramming,
+	"windowsterminal.exe":               models.CategoryProgramming,
+	"wsl.exe":                           models.CategoryProgramming,
+	"arduino.exe":                       models.CategoryProgramming,
+	"scratch desktop.exe":               models.CategoryProgramming,
+	"python3.exe":                       models.CategoryProgramming,
+	"sublime_text.exe":                  models.CategoryProgramming,
+	"androidstudio64.exe":               models.CategoryProgramming,
+	"unity.exe":                         models.CategoryProgramming,
+	"blender.exe":                       models.CategoryGraphics,
+	"photoshop.exe":                     models.CategoryGraphics,
+	"photoshopcc.exe":                   models.CategoryGraphics,
+	"illustrator.exe":                   models.CategoryGraphics,
+	"gimp-2.10.exe":                     models.CategoryGraphics,
+	"krita.exe":                         models.CategoryGraphics,
+	"mspaint.exe":                       models.CategoryGraphics,
+	"inkscape.exe":                      models.CategoryGraphics,
+	"vlc.exe":                           models.CategoryVideo,
+	"wmplayer.exe":                      models.CategoryVideo,
+	"spotify.exe":                       models.CategoryMusic,
+	"discord.exe":                       models.CategoryMessaging,
+	"telegram.exe":                      models.CategoryMessaging,
+	"slack.exe":                         models.CategoryMessaging,
+	"teams.exe":                         models.CategoryMessaging,
+	"zoom.exe":                          models.CategoryMessaging,
+	"whatsapp.exe":                      models.CategoryMessaging,
+	"steam.exe":                         models.CategoryGames,
+	"steamwebhelper.exe":                models.CategoryGames,
+	"epicgameslauncher.exe":             models.CategoryGames,
+	"minecraft.exe":                     models.CategoryGames,
+	"minecraftlauncher.exe":             models.CategoryGames,
+	"tlauncher.exe":                     models.CategoryGames,
+	"robloxplayerbeta.exe":              models.CategoryGames,
+	"cs2.exe":                           models.CategoryGames,
+	"dota2.exe":                         models.CategoryGames,
+	"valorant.exe":                      models.CategoryGames,
+	"gta5.exe":                          models.CategoryGames,
+	"fortniteclient-win64-shipping.exe": models.CategoryGames,
+}
+
+var defaultDomains = map[string]string{
+	"youtube.com":  models.CategoryVideo,
+	"youtu.be":     models.CategoryVideo,
+	"rutube.ru":    models.CategoryVideo,
+	"twitch.tv":    models.CategoryVideo,
+	"kinopoisk.ru": models.CategoryVideo,
+	"ivi.ru":       models.CategoryVideo,
+
+	"vk.com":        models.CategorySocial,
+	"ok.ru":         models.CategorySocial,
+	"facebook.com":  models.CategorySocial,
+	"instagram.com": models.CategorySocial,
+	"tiktok.com":    models.CategorySocial,
+	"twitter.com":   models.CategorySocial,
+	"x.com":         models.CategorySocial,
+	"reddit.com":    models.CategorySocial,
+	"pinterest.com": models.CategorySocial,
+
+	"web.telegram.org": models.CategoryMessaging,
+	"web.whatsapp.com": models.CategoryMessaging,
+	"discord.com":      models.CategoryMessaging,
+
+	"github.com":         models.CategoryProgramming,
+	"gitlab.com":         models.CategoryProgramming,
+	"stackoverflow.com":  models.CategoryProgramming,
+	"habr.com":           models.CategoryProgramming,
+	"replit.com":         models.CategoryProgramming,
+	"scratch.mit.edu":    models.CategoryProgramming,
+	"informatics.msk.ru": models.CategoryProgramming,
+	"codeforces.com":     models.CategoryProgramming,
+
+	"wikipedia.org":       models.CategoryEducation,
+	"uchi.ru":             models.CategoryEducation,
+	"resh.edu.ru":         models.CategoryEducation,
+	"foxford.ru":          models.CategoryEducation,
+	"yaklass.ru":          models.CategoryEducation,
+	"skysmart.ru":         models.CategoryEducation,
+	"sdamgia.ru":          models.CategoryEducation,
+	"dnevnik.ru":          models.CategoryEducation,
+	"education.yandex.ru": models.CategoryEducation,
+
+	"google.com":     models.CategorySearch,
+	"bing.com":       models.CategorySearch,
+	"yandex.ru":      models.CategorySearch,
+	"ya.ru":          models.CategorySearch,
+	"duckduckgo.com": models.CategorySearch,
+
+	"docs.google.com":  models.CategoryOffice,
+	"drive.google.com": models.CategoryOffice,
+	"office.com":       models.CategoryOffice,
+	"disk.yandex.ru":   models.CategoryOffice,
+
+	"music.yandex.ru": models.CategoryMusic,
+	"spotify.com":     models.CategoryMusic,
+
+	"store.steampowered.com": models.CategoryGames,
+	"steampowered.com":       models.CategoryGames,
+	"steamcommunity.com":     models.CategoryGames,
+	"roblox.com":             models.CategoryGames,
+	"minecraft.net":          models.CategoryGames,
+	"poki.com":               models.CategoryGames,
+	"crazygames.com":         models.CategoryGames,
+	"chess.com":              models.CategoryGames,
+}
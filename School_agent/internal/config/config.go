@@ -3,21 +3,160 @@ package config
 import (
 	"encoding/json"
 	"os"
+	"school_agent/internal/models"
 )
 
 const (
 	DefaultLogDir      = "C:\\ProgramData\\SchoolAgent\\Logs"
 	DefaultConfigPath  = "C:\\ProgramData\\SchoolAgent\\config.json"
 	DefaultProjectBase = "D:\\UserProjects"
+	DefaultStateDir    = "C:\\ProgramData\\SchoolAgent\\State"
 	PipeName           = `\\.\pipe\SchoolAgentIPC`
 )
 
+// Режимы выбора транспорта
+const (
+	TransportAuto      = "auto"
+	TransportWebSocket = "websocket"
+	TransportHTTPS     = "https"
+)
+
+// Что делать с командной строкой процесса в событиях
+const (
+	CommandLineFull     = "full"
+	CommandLineRedacted = "redacted" // скрыть пароли и токены по RedactPatterns
+	CommandLineExeOnly  = "exe_only" // только исполняемый файл, без аргументов
+	CommandLineNone     = "none"
+)
+
+// Источник списка процессов
+const (
+	ProcessSourceAuto     = "auto" // procfs на Linux, gopsutil на остальных ОС
+	ProcessSourceGopsutil = "gopsutil"
+	ProcessSourceProcfs   = "procfs"
+)
+
+// Сжатие выгружаемых логов
+const (
+	CompressionNone    = "none"
+	CompressionGzip    = "gzip"
+	CompressionDeflate = "deflate" // permessage-deflate на уровне WebSocket
+)
+
 type Config struct {
 	ServerURL   string `json:"server_url"`
 	DeviceToken string `json:"device_token"`
 	Hostname    string `json:"hostname"`
 	LogDir      string `json:"log_dir"`
 	ProjectBase string `json:"project_base"`
+	StateDir    string `json:"state_dir"`
+
+	// Transport: auto (WebSocket с переходом на HTTPS), websocket или https
+	Transport       string `json:"transport"`
+	HTTPFallbackURL string `json:"http_fallback_url"`
+
+	Proxy  ProxyConfig  `json:"proxy"`
+	Upload UploadConfig `json:"upload"`
+
+	Process    ProcessConfig    `json:"process"`
+	Foreground ForegroundConfig `json:"foreground"`
+	Resources  ResourceConfig   `json:"resources"`
+	Browser    BrowserConfig    `json:"browser"`
+	Watchlist  models.Watchlist `json:"watchlist"`
+	Policy     models.Policy    `json:"policy"`
+	// Лимиты считают только процессы из Watchlist (см. models.AppQuota)
+	Quotas     models.Quotas    `json:"quotas"`
+	Categories models.Categories `json:"categories"`
+	URLRules   models.URLRules   `json:"url_rules"`
+}
+
+// ProxyConfig — исходящий HTTP CONNECT прокси для связи с сервером
+type ProxyConfig struct {
+	URL      string   `json:"url"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	// Исключения: хосты, ".домен" и "*.домен", IP и CIDR, "host:port",
+	// "<local>" — имена без точки, как в настройках прокси Windows
+	NoProxy  []string `json:"no_proxy"`
+	// Если URL не задан — брать HTTP_PROXY/HTTPS_PROXY/NO_PROXY из окружения
+	FromEnvironment bool `json:"from_environment"`
+}
+
+// UploadConfig — параметры выгрузки логов на сервер
+type UploadConfig struct {
+	MaxChunkBytes int    `json:"max_chunk_bytes"`
+	Compression   string `json:"compression"`
+	// Лимит скорости выгрузки; 0 — без ограничений
+	BandwidthLimitKBps int `json:"bandwidth_limit_kbps"`
+	// Часы, когда действует лимит (уроки). Пусто — всегда.
+	LimitHours []models.TimeRange `json:"limit_hours"`
+}
+
+// ProcessConfig — какие метаданные процессов попадают в события
+type ProcessConfig struct {
+	Source string `json:"source"`
+	// Получать запуски и завершения процессов событиями ОС; опрос раз в
+	// ResyncSec только сверяет состояние. Без событий — опрос каждые 3 с.
+	Events      bool   `json:"events"`
+	ResyncSec   int    `json:"resync_sec"`
+	CommandLine string `json:"command_line"`
+	// Регулярные выражения, совпадения с которыми заменяются на ***;
+	// первая группа шаблона (имя параметра) сохраняется
+	RedactPatterns []string `json:"redact_patterns"`
+	// Сообщать только о процессах пользователя, сидящего за консолью
+	OnlyConsoleUser bool `json:"only_console_user"`
+	// Добавлять в события хеш и метаданные exe, отмечать переименованные программы
+	Identity bool `json:"identity"`
+}
+
+// ForegroundConfig — учет активного окна
+type ForegroundConfig struct {
+	Enabled     bool `json:"enabled"`
+	IntervalSec int  `json:"interval_sec"`
+	// Отрезки фокуса короче этого не пишутся в лог (но учитываются во времени)
+	MinLogSec int `json:"min_log_sec"`
+}
+
+// Форматы истории браузеров
+const (
+	BrowserChromium = "chromium" // History, профили в Local State
+	BrowserFirefox  = "firefox"  // places.sqlite, профили в profiles.ini
+)
+
+// BrowserDef — браузер, историю которого читает агент
+type BrowserDef struct {
+	Name string `json:"name"`
+	// Каталог профилей относительно домашнего каталога пользователя
+	Root   string `json:"root"`
+	Format string `json:"format"`
+	// Отключить встроенный браузер с тем же именем
+	Disabled bool `json:"disabled"`
+}
+
+// BrowserConfig — чтение истории браузеров
+type BrowserConfig struct {
+	// Дополняют встроенную таблицу; запись с тем же Name заменяет встроенную
+	Browsers []BrowserDef `json:"browsers"`
+	// Регулярные выражения для адресов и поисковых запросов, как у процессов
+	RedactPatterns []string `json:"redact_patterns"`
+	// На сколько минут назад читать историю профиля при первом запуске;
+	// дальше чтение продолжается с сохраненного места
+	BackfillMin int `json:"backfill_min"`
+}
+
+// Какие процессы опрашивать на потребление ресурсов
+const (
+	ResourcesTracked = "tracked"  // только из списка отслеживаемых
+	ResourcesAllUser = "all_user" // все процессы пользователей
+)
+
+// ResourceConfig — замеры CPU/памяти/диска и пороги алертов
+type ResourceConfig struct {
+	Enabled     bool                       `json:"enabled"`
+	Mode        string                     `json:"mode"`
+	IntervalSec int                        `json:"interval_sec"`
+	WindowSec   int                        `json:"window_sec"`
+	Alerts      []models.ResourceAlertRule `json:"alerts"`
 }
 
 func Load() *Config {
@@ -29,17 +168,121 @@ func Load() *Config {
 		Hostname:    host,
 		LogDir:      DefaultLogDir,
 		ProjectBase: DefaultProjectBase,
+		StateDir:    DefaultStateDir,
+		Transport:   TransportAuto,
+		Proxy:       ProxyConfig{FromEnvironment: true},
+		Upload: UploadConfig{
+			MaxChunkBytes: 256 * 1024,
+			Compression:   CompressionGzip,
+		},
+		Process: ProcessConfig{
+			Source:      ProcessSourceAuto,
+			Events:      true,
+			ResyncSec:   60,
+			Identity:    true,
+			CommandLine: CommandLineRedacted,
+			RedactPatterns: []string{
+				`(?i)((?:password|passwd|pwd|token|secret|apikey|api_key)[=:])\S+`,
+				`(?i)((?:--password|--token|--secret)\s+)\S+`,
+			},
+		},
+		Foreground: ForegroundConfig{
+			Enabled:     true,
+			IntervalSec: 1,
+			MinLogSec:   5,
+		},
+		Resources: ResourceConfig{
+			Enabled:     true,
+			Mode:        ResourcesAllUser,
+			IntervalSec: 10,
+			WindowSec:   600,
+		},
+		Browser: BrowserConfig{
+			BackfillMin: 60,
+			RedactPatterns: []string{
+				`(?i)([?&#;](?:access_token|id_token|token|auth|code|password|passwd|pwd|secret|session|sessionid|sid|api_?key)=)[^&#]*`,
+			},
+		},
 	}
 
-	file, err := os.Open(DefaultConfigPath)
+	// SCHOOL_AGENT_CONFIG позволяет запускать агента с другим конфигом (разработка, тесты)
+	configPath := DefaultConfigPath
+	if p := os.Getenv("SCHOOL_AGENT_CONFIG"); p != "" {
+		configPath = p
+	}
+
+	file, err := os.Open(configPath)
 	if err == nil {
 		defer file.Close()
 		json.NewDecoder(file).Decode(cfg)
 	}
+
+	// Списки структур заполняем после чтения файла: json переиспользует
+	// элементы существующего слайса и оставил бы в них поля из дефолтов
+	if cfg.Resources.Alerts == nil {
+		cfg.Resources.Alerts = []models.ResourceAlertRule{
+			// Порог CPU — в процентах одного ядра
+			{ID: "cpu-unknown", Metric: models.MetricCPU, Above: 80, ForSec: 600, UnknownOnly: true},
+		}
+	}
+	if cfg.Watchlist.Rules == nil {
+		def := DefaultWatchlist()
+		cfg.Watchlist.Rules = def.Rules
+		if cfg.Watchlist.Version == "" {
+			cfg.Watchlist.Version = def.Version
+		}
+	}
+	if cfg.URLRules.Rules == nil {
+		def := DefaultURLRules()
+		cfg.URLRules.Rules = def.Rules
+		if cfg.URLRules.Version == "" {
+			cfg.URLRules.Version = def.Version
+		}
+	}
 	
 	// Гарантируем, что папки существуют
 	os.MkdirAll(cfg.LogDir, 0755)
 	os.MkdirAll(cfg.ProjectBase, 0755)
+	os.MkdirAll(cfg.StateDir, 0755)
 
 	return cfg
-}
\ No newline at end of file
+}
+
+// DefaultWatchlist — программы, которые агент отслеживает без настройки с сервера
+func DefaultWatchlist() models.Watchlist {
+	names := []string{
+		"chrome.exe", "msedge.exe", "firefox.exe",
+		"Code.exe", "notepad.exe", "notepad++.exe",
+		"WINWORD.EXE", "EXCEL.EXE", "POWERPNT.EXE",
+		"AcroRd32.exe", "Acrobat.exe",
+		"PhotoshopCC.exe", "Photoshop.exe", "Illustrator.exe",
+		"vlc.exe", "steam.exe", "Discord.exe", "Telegram.exe", "Spotify.exe",
+		"cmd.exe", "powershell.exe", "python.exe", "java.exe", "javaw.exe",
+		"node.exe", "git.exe", "VisualStudio.exe", "devenv.exe",
+		"Slack.exe", "Teams.exe", "Zoom.exe",
+	}
+
+	wl := models.Watchlist{Version: "builtin"}
+	for _, n := range names {
+		wl.Rules = append(wl.Rules, models.WatchRule{Type: models.RuleName, Pattern: n})
+	}
+	return wl
+}
+
+// DefaultURLRules — не писать служебные страницы браузеров и встроенные данные
+func DefaultURLRules() models.URLRules {
+	return models.URLRules{
+		Version: "builtin",
+		Rules: []models.URLRule{
+			{
+				ID:     "browser-pages",
+				Action: models.URLExclude,
+				Schemes: []string{
+					"about", "chrome", "edge", "opera", "brave", "vivaldi", "browser",
+					"chrome-extension", "moz-extension", "extension", "view-source",
+				},
+			},
+			{ID: "inline-data", Action: models.URLExclude, Schemes: []string{"data", "blob", "javascript"}},
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"school_agent/internal/models"
+	"testing"
+)
+
+// loadJSON читает конфиг из файла с каталогами во временной папке
+func loadJSON(t *testing.T, body string) *Config {
+	t.Helper()
+	dir := t.TempDir()
+	dirs := `"log_dir": ` + quote(filepath.Join(dir, "logs")) +
+		`, "project_base": ` + quote(filepath.Join(dir, "projects")) +
+		`, "state_dir": ` + quote(filepath.Join(dir, "state"))
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte("{"+dirs+", "+body+"}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SCHOOL_AGENT_CONFIG", path)
+	return Load()
+}
+
+func quote(s string) string {
+	return `"` + filepath.ToSlash(s) + `"`
+}
+
+func TestLoadURLRulesWithoutDefaults(t *testing.T) {
+	cfg := loadJSON(t, `"url_rules": {"version": "v1", "rules": [
+		{"id": "no-games", "action": "exclude", "domains": ["games.example"]}
+	]}`)
+
+	rules := cfg.URLRules.Rules
+	if len(rules) != 1 {
+		t.Fatalf("rules: got %d, want 1", len(rules))
+	}
+	if r := rules[0]; r.Schemes != nil || len(r.Domains) != 1 || r.Action != models.URLExclude {
+		t.Fatalf("user rule mixed with builtin: %+v", r)
+	}
+	if cfg.URLRules.Version != "v1" {
+		t.Errorf("version: got %q", cfg.URLRules.Version)
+	}
+
+	cfg = loadJSON(t, `"transport": "auto"`)
+	if cfg.URLRules.Version != "builtin" || len(cfg.URLRules.Rules) != len(DefaultURLRules().Rules) {
+		t.Errorf("defaults without url_rules: %+v", cfg.URLRules)
+	}
+}
+
+func TestLoadWatchlistWithoutDefaults(t *testing.T) {
+	cfg := loadJSON(t, `"watchlist": {"version": "w1", "rules": [
+		{"pattern": "minecraft.exe"},
+		{"type": "glob", "pattern": "*craft*.exe"}
+	]}`)
+
+	rules := cfg.Watchlist.Rules
+	if len(rules) != 2 {
+		t.Fatalf("rules: got %d, want 2", len(rules))
+	}
+	// Тип не задан — остается пустым, а не берется из встроенного правила
+	if rules[0].Type != "" || rules[0].Pattern != "minecraft.exe" || rules[1].Type != models.RuleGlob {
+		t.Fatalf("user rules mixed with builtin: %+v", rules)
+	}
+
+	cfg = loadJSON(t, `"watchlist": {"all_user_processes": true}`)
+	if !cfg.Watchlist.AllUserProcesses || cfg.Watchlist.Version != "builtin" || len(cfg.Watchlist.Rules) != len(DefaultWatchlist().Rules) {
+		t.Errorf("defaults with partial watchlist: %+v", cfg.Watchlist)
+	}
+}
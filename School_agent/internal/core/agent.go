@@ -1,18 +1,21 @@
 package core
 
 import (
-	"bufio"
-	"encoding/json"
 	"log"
-	"os"
+	"school_agent/internal/category"
 	"school_agent/internal/config"
+	"school_agent/internal/ipc"
 	"school_agent/internal/logger"
 	"school_agent/internal/models"
 	"school_agent/internal/monitor"
 	"school_agent/internal/session"
 	"school_agent/internal/sysuser"
+	"school_agent/internal/upload"
+	"school_agent/internal/userhelper"
+	"school_agent/internal/usage"
 	"school_agent/internal/ws"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -21,9 +24,26 @@ type Agent struct {
 	logMgr      *logger.Manager
 	wsClient    *ws.Client
 	sessionMgr  *session.Manager
+	uploader    *upload.Uploader
+	ipcServer   *ipc.Server
+	ipcChan     chan models.IPCMessage
+	usage       *usage.Tracker
+	categories  *category.Database
+	quotas      *usage.QuotaEnforcer
 	
 	procMonitor    *monitor.ProcessMonitor
+	policy         *monitor.ProcessPolicy
 	browserMonitor *monitor.BrowserMonitor
+	fgMonitor      *monitor.ForegroundMonitor
+	fgSource       *monitor.ReportedForeground
+	fgHelper       *userhelper.Launcher
+	resMonitor     *monitor.ResourceMonitor
+
+	alertMu       sync.Mutex
+	pendingAlerts []map[string]interface{}
+
+	summaryMu        sync.Mutex
+	pendingSummaries []models.UsageSummary
 	
 	currentUser string
 	stopChan    chan struct{}
@@ -35,18 +55,64 @@ func New() *Agent {
 	agent := &Agent{
 		cfg:        cfg,
 		logMgr:     logger.New(cfg.LogDir, cfg.Hostname),
-		wsClient:   ws.New(cfg.ServerURL, cfg.DeviceToken, cfg.Hostname),
+		wsClient:   ws.New(cfg),
 		sessionMgr: session.New(cfg.ProjectBase),
+		ipcChan:    make(chan models.IPCMessage, 10),
+		usage:      usage.New(cfg.StateDir),
 		stopChan:   make(chan struct{}),
 	}
+	agent.ipcServer = ipc.New(agent.ipcChan)
+	agent.uploader = upload.New(cfg.Upload, cfg.Hostname, cfg.StateDir, agent.wsClient)
 
-	agent.procMonitor = monitor.NewProcessMonitor(func(action, program string) {
-		agent.logMgr.Add(agent.currentUser, "process", program, action)
+	agent.categories = category.New()
+	loadRules(agent, agent.categoryRules())
+	agent.loadState(pendingSummariesFile, &agent.pendingSummaries)
+
+	agent.procMonitor = monitor.NewProcessMonitor(cfg.Process, monitor.NewProcessSource(cfg.Process.Source), func(action, program string, details *models.ProcessDetails) {
+		agent.logMgr.AddEntry(models.LogEntry{
+			Username: agent.currentUser,
+			LogType:  "process",
+			Program:  program,
+			Action:   action,
+			Process:  details,
+			Category: agent.categories.ForProcess(program, details.Identity),
+		})
+		agent.trackUsage(action, program, details)
 	})
+	agent.procMonitor.SetTreeAlertHandler(agent.onTreeAlert)
+	loadRules(agent, agent.watchlistRules())
 
-	agent.browserMonitor = monitor.NewBrowserMonitor("", func(browser, action string) {
-		agent.logMgr.Add(agent.currentUser, "browser", browser, action)
+	agent.policy = monitor.NewProcessPolicy(agent.warnUser, func(program, action string) {
+		agent.logMgr.Add(agent.currentUser, "policy", program, action)
 	})
+	agent.procMonitor.AttachPolicy(agent.policy)
+	loadRules(agent, agent.policyRules())
+
+	agent.quotas = usage.NewQuotaEnforcer(cfg.StateDir, agent.usage, agent.warnUser, agent.procMonitor.Terminate, func(program, action string) {
+		agent.logMgr.Add(agent.currentUser, "quota", program, action)
+	})
+	agent.quotas.SetCategorizer(agent.categories.ForApp)
+	loadRules(agent, agent.quotaRules())
+	agent.ipcServer.Handle("get_quota", agent.handleQuotaQuery)
+
+	agent.browserMonitor = monitor.NewBrowserMonitor(cfg.Browser, cfg.StateDir, "", agent.onBrowserVisit)
+	loadRules(agent, agent.urlRules())
+
+	if cfg.Resources.Enabled {
+		agent.resMonitor = monitor.NewResourceMonitor(cfg.Resources, agent.procMonitor, agent.onResourceAlert)
+	}
+
+	if cfg.Foreground.Enabled {
+		interval := time.Duration(cfg.Foreground.IntervalSec) * time.Second
+		if interval <= 0 {
+			interval = time.Second
+		}
+		// Окно сообщает помощник из сессии пользователя; пропуск трех отчетов — фокуса нет
+		agent.fgSource = monitor.NewReportedForeground(3 * interval)
+		agent.fgHelper = userhelper.NewLauncher(interval)
+		agent.fgMonitor = monitor.NewForegroundMonitor(agent.fgSource, interval, agent.onFocus)
+		agent.ipcServer.Handle(userhelper.CmdForeground, agent.handleForegroundReport)
+	}
 
 	return agent
 }
@@ -54,15 +120,25 @@ func New() *Agent {
 func (a *Agent) Run() {
 	a.logMgr.Start()
 	a.wsClient.Start(a.stopChan)
+	a.ipcServer.Start()
 
 	a.detectAndUpdateUser()
+	a.ensureForegroundHelper()
 
 	a.procMonitor.Start()
+	a.quotas.Start()
 	a.browserMonitor.Start()
+	if a.fgMonitor != nil {
+		a.fgMonitor.Start()
+	}
+	if a.resMonitor != nil {
+		a.resMonitor.Start()
+	}
 
 	hbTicker := time.NewTicker(30 * time.Second)
 	uploadTicker := time.NewTicker(10 * time.Minute)
 	userCheckTicker := time.NewTicker(30 * time.Second)
+	usageTicker := time.NewTicker(time.Minute)
 
 	log.Println("Core Agent logic started")
 
@@ -74,14 +150,26 @@ func (a *Agent) Run() {
 		case cmd := <-a.wsClient.CommandChan:
 			a.handleWSCommand(cmd)
 
+		case <-a.wsClient.ConnectedChan:
+			a.flushAlerts()
+			a.flushSummaries()
+
+		case msg := <-a.ipcChan:
+			a.handleIPCMessage(msg)
+
 		case <-hbTicker.C:
 			a.wsClient.SendHeartbeat(a.currentUser)
 
 		case <-uploadTicker.C:
-			a.UploadLogs()
+			// В отдельной горутине: выгрузка ждет LOGS_ACK, которые приходят через этот же цикл
+			go a.UploadLogs()
 
 		case <-userCheckTicker.C:
 			a.detectAndUpdateUser()
+			a.ensureForegroundHelper()
+
+		case <-usageTicker.C:
+			a.tickUsage()
 		}
 	}
 }
@@ -91,6 +179,9 @@ func (a *Agent) Wait() {
 }
 
 func (a *Agent) Stop() {
+	if a.fgHelper != nil {
+		a.fgHelper.Stop()
+	}
 	close(a.stopChan)
 }
 
@@ -98,40 +189,48 @@ func (a *Agent) handleWSCommand(cmd models.WSCommand) {
 	switch cmd.Type {
 	case "UPLOAD_LOGS":
 		go a.UploadLogs()
+	case "LOGS_ACK":
+		a.uploader.Ack(cmd)
 	case "GET_USER":
 		a.wsClient.SendHeartbeat(a.currentUser)
+	case "SET_WATCHLIST":
+		applyRules(a, a.watchlistRules(), cmd.Watchlist)
+	case "GET_WATCHLIST":
+		reportRules(a, a.watchlistRules())
+	case "SET_POLICY":
+		applyRules(a, a.policyRules(), cmd.Policy)
+	case "GET_POLICY":
+		reportRules(a, a.policyRules())
+	case "SET_QUOTAS":
+		applyRules(a, a.quotaRules(), cmd.Quotas)
+	case "GET_QUOTAS":
+		reportRules(a, a.quotaRules())
+	case "SET_CATEGORIES":
+		applyRules(a, a.categoryRules(), cmd.Categories)
+	case "GET_CATEGORIES":
+		reportRules(a, a.categoryRules())
+	case "SET_URL_RULES":
+		applyRules(a, a.urlRules(), cmd.URLRules)
+	case "GET_URL_RULES":
+		reportRules(a, a.urlRules())
+	case "GET_URL_RULES_REPORT":
+		a.sendURLRulesReport()
+	case "GET_USAGE":
+		a.handleGetUsage(cmd)
+	case "GET_RESOURCES":
+		a.handleGetResources()
 	}
 }
 
-func (a *Agent) UploadLogs() {
-	logFile := a.logMgr.GetCurrentLogFile()
-	file, err := os.Open(logFile)
-	if err != nil { return }
-	defer file.Close()
-
-	var logs []models.LogEntry
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		var entry models.LogEntry
-		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
-			logs = append(logs, entry)
-		}
+func (a *Agent) handleIPCMessage(msg models.IPCMessage) {
+	switch msg.Command {
+	case "log":
+		a.logMgr.Add(a.ipcUser(msg), "shell", msg.Program, msg.Action)
 	}
+}
 
-	if len(logs) > 0 {
-		for i := range logs {
-			if logs[i].DeviceName == "" {
-				logs[i].DeviceName = a.cfg.Hostname
-			}
-		}
-
-		payload := map[string]interface{}{
-			"type": "logs",
-			"data": logs,
-		}
-		a.wsClient.SendJSON(payload)
-		log.Printf("Uploaded %d logs to server", len(logs))
-	}
+func (a *Agent) UploadLogs() {
+	a.uploader.Upload(a.logMgr.GetCurrentLogFile())
 }
 
 func (a *Agent) detectAndUpdateUser() {
@@ -147,8 +246,11 @@ func (a *Agent) detectAndUpdateUser() {
 		if a.currentUser != "" {
 			log.Printf("User logged out: %s", a.currentUser)
 			a.logMgr.Add(a.currentUser, "system", "agent", "Session End")
+			a.sendUsageSummary(a.currentUser, time.Now())
 			a.currentUser = ""
 			a.browserMonitor.UpdateUsername("")
+			a.policy.UpdateUsername("")
+			a.procMonitor.UpdateUsername("")
 			a.wsClient.SendHeartbeat("")
 		}
 		return
@@ -158,17 +260,29 @@ func (a *Agent) detectAndUpdateUser() {
 		if a.currentUser != "" {
 			log.Printf("User changed: %s -> %s", a.currentUser, user)
 			a.logMgr.Add(a.currentUser, "system", "agent", "Session End")
+			a.sendUsageSummary(a.currentUser, time.Now())
 		} else {
 			log.Printf("User logged in: %s", user)
 		}
 		
 		a.currentUser = user
 		a.browserMonitor.UpdateUsername(user)
+		a.policy.UpdateUsername(user)
+		a.procMonitor.UpdateUsername(user)
 		a.sessionMgr.PrepareUserEnvironment(user)
 		a.logMgr.Add(user, "system", "agent", "Session Start")
 		a.wsClient.SendHeartbeat(a.currentUser)
 	}
 }
+// ipcUser — отправитель сообщения IPC. Сервер подписывает сообщения учетной
+// записью процесса клиента; регистр имени приводится к активному пользователю.
+func (a *Agent) ipcUser(msg models.IPCMessage) string {
+	if strings.EqualFold(msg.User, a.currentUser) {
+		return a.currentUser
+	}
+	return msg.User
+}
+
 func (a *Agent) cleanUsername(username string) string {
 	if idx := strings.Index(username, "\\"); idx != -1 {
 		return username[idx+1:]
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"fmt"
+	"school_agent/internal/models"
+)
+
+// onBrowserVisit пишет посещение страницы с категорией сайта, а для
+// страниц выдачи поисковиков — еще и событие search с запросом
+func (a *Agent) onBrowserVisit(v models.BrowserVisit) {
+	action := fmt.Sprintf("Visited: %s", v.URL)
+	if v.Title != "" && len(v.Title) < 100 {
+		action = fmt.Sprintf("Visited: %s (%s)", v.URL, v.Title)
+	}
+	if v.Profile != "" {
+		action += fmt.Sprintf(" [profile %s]", v.Profile)
+	}
+	a.logMgr.AddEntry(models.LogEntry{
+		Username: a.currentUser,
+		LogType:  "browser",
+		Program:  v.Browser,
+		Action:   action,
+		Category: a.categories.ForURL(v.URL),
+		Browser:  &v,
+	})
+
+	if v.Query != "" {
+		a.logMgr.AddEntry(models.LogEntry{
+			Username: a.currentUser,
+			LogType:  "search",
+			Program:  v.Browser,
+			Action:   fmt.Sprintf("Searched %s: %s", v.SearchEngine, v.Query),
+			Category: models.CategorySearch,
+			Browser:  &v,
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"fmt"
+	"school_agent/internal/models"
+	"school_agent/internal/monitor"
+	"time"
+)
+
+// onFocus получает законченный отрезок фокуса окна
+func (a *Agent) onFocus(w monitor.ForegroundWindow, seconds float64) {
+	user := a.currentUser
+	if user == "" || w.App == "" {
+		return
+	}
+
+	a.usage.AddFocus(user, w.App, seconds, time.Now())
+
+	if seconds >= float64(a.cfg.Foreground.MinLogSec) {
+		a.logMgr.AddEntry(models.LogEntry{
+			Username: user,
+			LogType:  "focus",
+			Program:  w.App,
+			Action:   fmt.Sprintf("Focused %.0fs: %s", seconds, w.Title),
+			Category: a.categories.ForApp(w.App),
+		})
+	}
+}
+
+// ensureForegroundHelper держит помощник фокуса запущенным в сессии пользователя
+func (a *Agent) ensureForegroundHelper() {
+	if a.fgHelper != nil {
+		a.fgHelper.Ensure()
+	}
+}
+
+// handleForegroundReport принимает активное окно от помощника
+func (a *Agent) handleForegroundReport(msg models.IPCMessage) models.IPCMessage {
+	// Фокус сообщает только помощник в сессии активного пользователя
+	if a.ipcUser(msg) != a.currentUser {
+		return models.IPCMessage{Command: "denied"}
+	}
+	w := monitor.ForegroundWindow{PID: msg.PID, App: msg.Program, Title: msg.Title}
+	a.fgSource.Report(w, w.App != "")
+	return models.IPCMessage{Command: "ok"}
+}
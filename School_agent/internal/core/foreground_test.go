@@ -0,0 +1,26 @@
+package core
+
+import (
+	"school_agent/internal/models"
+	"school_agent/internal/monitor"
+	"testing"
+	"time"
+)
+
+// Отчет о фокусе принимается только от процесса активного пользователя
+func TestForegroundReportFromOtherUserIgnored(t *testing.T) {
+	a := offlineAgent(t)
+	a.fgSource = monitor.NewReportedForeground(time.Minute)
+
+	if reply := a.handleForegroundReport(models.IPCMessage{Command: "foreground", User: "teacher", Program: "game.exe"}); reply.Command != "denied" {
+		t.Fatalf("reply from another user: %+v", reply)
+	}
+	if _, ok, _ := a.fgSource.Foreground(); ok {
+		t.Fatal("report from another user accepted")
+	}
+
+	a.handleForegroundReport(models.IPCMessage{Command: "foreground", User: "Pupil", Program: "word.exe"})
+	if w, ok, _ := a.fgSource.Foreground(); !ok || w.App != "word.exe" {
+		t.Fatalf("report from the active user: %+v %v", w, ok)
+	}
+}
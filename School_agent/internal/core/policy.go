@@ -0,0 +1,14 @@
+package core
+
+import "school_agent/internal/models"
+
+// warnUser показывает предупреждение в оболочке пользователя user
+func (a *Agent) warnUser(user, program, message string, countdown int) bool {
+	return a.ipcServer.Notify(user, models.IPCMessage{
+		Command:   "warning",
+		User:      user,
+		Program:   program,
+		Message:   message,
+		Countdown: countdown,
+	})
+}
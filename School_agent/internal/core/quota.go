@@ -0,0 +1,16 @@
+package core
+
+import (
+	"school_agent/internal/models"
+	"time"
+)
+
+// handleQuotaQuery отвечает оболочке, сколько времени осталось по лимитам
+func (a *Agent) handleQuotaQuery(req models.IPCMessage) models.IPCMessage {
+	user := a.ipcUser(req)
+	return models.IPCMessage{
+		Command: "quota",
+		User:    user,
+		Quotas:  a.quotas.Status(user, time.Now()),
+	}
+}
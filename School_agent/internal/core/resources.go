@@ -0,0 +1,64 @@
+package core
+
+import (
+	"fmt"
+	"log"
+	"school_agent/internal/models"
+)
+
+// Сколько алертов держим до переподключения; старые вытесняются новыми
+const maxPendingAlerts = 100
+
+// onResourceAlert отправляет алерт серверу сразу, не дожидаясь выгрузки логов.
+// Без соединения алерт ждет в очереди до переподключения.
+func (a *Agent) onResourceAlert(alert models.ResourceAlert) {
+	a.logMgr.Add(a.currentUser, "alert", alert.Process.Name,
+		fmt.Sprintf("Resource alert %s: %s=%.1f above %.1f for %ds", alert.Rule, alert.Metric, alert.Value, alert.Threshold, alert.ForSec))
+
+	a.queueAlert(map[string]interface{}{
+		"priority": "high",
+		"device":   a.cfg.Hostname,
+		"user":     a.currentUser,
+		"alert":    alert,
+	})
+}
+
+// queueAlert ставит алерт в очередь и сразу пытается ее отправить
+func (a *Agent) queueAlert(payload map[string]interface{}) {
+	a.alertMu.Lock()
+	a.pendingAlerts = append(a.pendingAlerts, payload)
+	a.alertMu.Unlock()
+	a.flushAlerts()
+}
+
+// flushAlerts отправляет очередь алертов по порядку; неотправленные
+// возвращаются в начало очереди
+func (a *Agent) flushAlerts() {
+	a.alertMu.Lock()
+	pending := a.pendingAlerts
+	a.pendingAlerts = nil
+	a.alertMu.Unlock()
+
+	for i, payload := range pending {
+		if err := a.wsClient.SendJSON(a.wsClient.Encoder().Message("alert", payload)); err != nil {
+			a.alertMu.Lock()
+			a.pendingAlerts = append(pending[i:len(pending):len(pending)], a.pendingAlerts...)
+			if drop := len(a.pendingAlerts) - maxPendingAlerts; drop > 0 {
+				log.Printf("Alert queue is full, dropping %d oldest alerts", drop)
+				a.pendingAlerts = a.pendingAlerts[drop:]
+			}
+			a.alertMu.Unlock()
+			return
+		}
+	}
+}
+
+func (a *Agent) handleGetResources() {
+	if a.resMonitor == nil {
+		return
+	}
+	a.wsClient.SendJSON(a.wsClient.Encoder().Message("resources", map[string]interface{}{
+		"device":    a.cfg.Hostname,
+		"processes": a.resMonitor.Top(20),
+	}))
+}
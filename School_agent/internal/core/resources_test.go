@@ -0,0 +1,50 @@
+package core
+
+import (
+	"fmt"
+	"school_agent/internal/category"
+	"school_agent/internal/config"
+	"school_agent/internal/logger"
+	"school_agent/internal/models"
+	"school_agent/internal/ws"
+	"testing"
+)
+
+// offlineAgent — агент без соединения с сервером
+func offlineAgent(t *testing.T) *Agent {
+	cfg := &config.Config{ServerURL: "ws://localhost:1/ws", Hostname: "pc-01", StateDir: t.TempDir()}
+	a := &Agent{cfg: cfg, wsClient: ws.New(cfg), logMgr: logger.New(t.TempDir(), cfg.Hostname), categories: category.New(), currentUser: "pupil"}
+	a.logMgr.Start()
+	return a
+}
+
+func TestResourceAlertsQueuedOffline(t *testing.T) {
+	a := offlineAgent(t)
+
+	// Соединения нет: алерты копятся, старые вытесняются
+	for i := 0; i < maxPendingAlerts+5; i++ {
+		a.onResourceAlert(models.ResourceAlert{Rule: fmt.Sprint(i), Process: models.ResourceStats{Name: "miner.exe"}})
+	}
+
+	if len(a.pendingAlerts) != maxPendingAlerts {
+		t.Fatalf("pending: got %d, want %d", len(a.pendingAlerts), maxPendingAlerts)
+	}
+	first := a.pendingAlerts[0]
+	if alert := first["alert"].(models.ResourceAlert); alert.Rule != "5" || first["user"] != "pupil" {
+		t.Fatalf("oldest kept alert: %+v", first)
+	}
+}
+
+func TestTreeAlertsQueuedOffline(t *testing.T) {
+	a := offlineAgent(t)
+
+	a.onResourceAlert(models.ResourceAlert{Rule: "cpu", Process: models.ResourceStats{Name: "miner.exe"}})
+	a.onTreeAlert("t1", "cmd.exe", &models.ProcessDetails{Ancestry: []string{"cmd.exe", "winword.exe"}})
+
+	if len(a.pendingAlerts) != 2 {
+		t.Fatalf("pending: got %d, want 2", len(a.pendingAlerts))
+	}
+	if tree := a.pendingAlerts[1]; tree["kind"] != "process_tree" || tree["rule"] != "t1" || tree["user"] != "pupil" {
+		t.Fatalf("tree alert: %+v", tree)
+	}
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"log"
+	"school_agent/internal/models"
+)
+
+// ruleSet — набор правил, который берется из конфига, заменяется командой
+// SET_* от сервера и сохраняется в каталоге состояния до следующего запуска
+type ruleSet[T any] struct {
+	name   string // для журнала
+	file   string // файл в каталоге состояния
+	status string // тип сообщения о действующей версии
+
+	config  T
+	version func(set T) string
+	apply   func(set T) error
+	// active — версия, которая действует сейчас
+	active func() string
+	// keep — сохранять ли принятый набор; nil — всегда
+	keep func(set T) bool
+}
+
+func (rs ruleSet[T]) persistent(set T) bool {
+	return rs.keep == nil || rs.keep(set)
+}
+
+// loadRules применяет набор, полученный от сервера ранее,
+// или набор из конфига, если сервер его еще не присылал
+func loadRules[T any](a *Agent, rs ruleSet[T]) {
+	set := rs.config
+
+	var saved T
+	if a.loadState(rs.file, &saved) && rs.persistent(saved) {
+		set = saved
+	}
+
+	if err := rs.apply(set); err != nil {
+		log.Printf("Invalid %s %q: %v, using config", rs.name, rs.version(set), err)
+		rs.apply(rs.config)
+	}
+}
+
+// applyRules применяет набор из команды SET_* и сообщает серверу результат.
+// При ошибке продолжает действовать прежний набор.
+func applyRules[T any](a *Agent, rs ruleSet[T], set *T) {
+	if set == nil {
+		return
+	}
+
+	err := rs.apply(*set)
+	if err != nil {
+		log.Printf("Rejected %s %q: %v", rs.name, rs.version(*set), err)
+	} else if rs.persistent(*set) {
+		a.saveState(rs.file, *set)
+	}
+	a.sendRulesStatus(rs.status, rs.active(), rs.version(*set), err)
+}
+
+// reportRules отвечает на GET_*: какая версия правил сейчас действует
+func reportRules[T any](a *Agent, rs ruleSet[T]) {
+	a.sendRulesStatus(rs.status, rs.active(), "", nil)
+}
+
+func (a *Agent) sendRulesStatus(msgType, version, requested string, applyErr error) {
+	fields := map[string]interface{}{
+		"device":    a.cfg.Hostname,
+		"version":   version,
+		"requested": requested,
+		"applied":   applyErr == nil,
+	}
+	if applyErr != nil {
+		fields["error"] = applyErr.Error()
+	}
+	a.wsClient.SendJSON(a.wsClient.Encoder().Message(msgType, fields))
+}
+
+func (a *Agent) watchlistRules() ruleSet[models.Watchlist] {
+	return ruleSet[models.Watchlist]{
+		name:    "watchlist",
+		file:    "watchlist.json",
+		status:  "watchlist_status",
+		config:  a.cfg.Watchlist,
+		version: func(wl models.Watchlist) string { return wl.Version },
+		apply:   a.procMonitor.SetWatchlist,
+		active:  a.procMonitor.WatchlistVersion,
+	}
+}
+
+func (a *Agent) policyRules() ruleSet[models.Policy] {
+	return ruleSet[models.Policy]{
+		name:    "policy",
+		file:    "policy.json",
+		status:  "policy_status",
+		config:  a.cfg.Policy,
+		version: func(p models.Policy) string { return p.Version },
+		apply:   a.policy.Apply,
+		active:  a.policy.Version,
+	}
+}
+
+func (a *Agent) quotaRules() ruleSet[models.Quotas] {
+	return ruleSet[models.Quotas]{
+		name:    "quotas",
+		file:    "quotas.json",
+		status:  "quotas_status",
+		config:  a.cfg.Quotas,
+		version: func(q models.Quotas) string { return q.Version },
+		apply:   a.quotas.Apply,
+		active:  a.quotas.Version,
+	}
+}
+
+func (a *Agent) categoryRules() ruleSet[models.Categories] {
+	return ruleSet[models.Categories]{
+		name:    "categories",
+		file:    "categories.json",
+		status:  "categories_status",
+		config:  a.cfg.Categories,
+		version: func(c models.Categories) string { return c.Version },
+		apply: func(c models.Categories) error {
+			a.categories.Apply(c)
+			return nil
+		},
+		active: a.categories.Version,
+	}
+}
+
+func (a *Agent) urlRules() ruleSet[models.URLRules] {
+	return ruleSet[models.URLRules]{
+		name:    "URL rules",
+		file:    "url_rules.json",
+		status:  "url_rules_status",
+		config:  a.cfg.URLRules,
+		version: func(r models.URLRules) string { return r.Version },
+		apply:   a.browserMonitor.SetURLRules,
+		active:  a.browserMonitor.URLRulesVersion,
+		// Набор dry run проверяется до перезапуска и не становится действующим,
+		// даже если сохранен прежней версией агента
+		keep: func(r models.URLRules) bool { return !r.DryRun },
+	}
+}
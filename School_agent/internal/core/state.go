@@ -0,0 +1,24 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// loadState читает JSON-файл из каталога состояния агента
+func (a *Agent) loadState(name string, v interface{}) bool {
+	data, err := os.ReadFile(filepath.Join(a.cfg.StateDir, name))
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func (a *Agent) saveState(name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(a.cfg.StateDir, name), data, 0644)
+}
@@ -0,0 +1,13 @@
+package core
+
+// sendURLRulesReport — сколько адресов совпало с каждым правилом; нужен
+// прежде всего для проверки новых правил в режиме dry run
+func (a *Agent) sendURLRulesReport() {
+	version, dryRun, report := a.browserMonitor.URLRulesReport()
+	a.wsClient.SendJSON(a.wsClient.Encoder().Message("url_rules_report", map[string]interface{}{
+		"device":  a.cfg.Hostname,
+		"version": version,
+		"dry_run": dryRun,
+		"rules":   report,
+	}))
+}
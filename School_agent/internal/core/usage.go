@@ -0,0 +1,109 @@
+package core
+
+import (
+	"log"
+	"school_agent/internal/models"
+	"school_agent/internal/usage"
+	"time"
+)
+
+func (a *Agent) trackUsage(action, program string, details *models.ProcessDetails) {
+	user := a.currentUser
+	if user == "" || details == nil {
+		return
+	}
+
+	switch action {
+	case "Opened":
+		a.usage.ProcessOpened(user, program, details, time.Now())
+	case "Closed":
+		end := time.Now()
+		if details.EndTime != nil {
+			end = *details.EndTime
+		}
+		a.usage.ProcessClosed(user, program, usage.InstanceKey(details), end)
+	}
+}
+
+// Итоги дня, не отправленные из-за обрыва связи, хранятся в каталоге
+// состояния и отправляются после переподключения
+const pendingSummariesFile = "pending_summaries.json"
+
+// tickUsage сохраняет счетчики и в конце дня отправляет итоги за прошедшие сутки
+func (a *Agent) tickUsage() {
+	now := time.Now()
+	// Фокус активного окна досчитывается до закрытия дня, иначе он попадет
+	// в уже отправленные итоги только при следующей смене окна
+	if a.fgMonitor != nil && a.usage.Day() != now.Format("2006-01-02") {
+		a.fgMonitor.Flush(now)
+	}
+	for _, s := range a.usage.Tick(now) {
+		a.queueSummary(s)
+	}
+}
+
+// sendUsageSummary закрывает сессии вышедшего пользователя и отправляет его итоги за день
+func (a *Agent) sendUsageSummary(user string, now time.Time) {
+	if a.fgMonitor != nil {
+		a.fgMonitor.Flush(now)
+	}
+	a.usage.EndUser(user, now)
+	a.queueSummary(a.usage.Summary(now.Format("2006-01-02"), user, now))
+}
+
+func (a *Agent) handleGetUsage(cmd models.WSCommand) {
+	now := time.Now()
+	date := cmd.Date
+	if date == "" {
+		date = now.Format("2006-01-02")
+	}
+	for _, user := range a.usage.Users(date) {
+		a.sendSummary(a.usage.Summary(date, user, now))
+	}
+}
+
+func (a *Agent) sendSummary(s models.UsageSummary) error {
+	s.Device = a.cfg.Hostname
+	a.categories.Summarize(&s)
+	return a.wsClient.SendJSON(a.wsClient.Encoder().Message("usage_summary", map[string]interface{}{
+		"summary": s,
+	}))
+}
+
+// queueSummary сохраняет итоги до подтверждения отправки: сводка
+// за день не должна теряться, если в полночь нет связи
+func (a *Agent) queueSummary(s models.UsageSummary) {
+	a.summaryMu.Lock()
+	a.pendingSummaries = append(a.pendingSummaries, s)
+	a.saveState(pendingSummariesFile, a.pendingSummaries)
+	a.summaryMu.Unlock()
+	a.flushSummaries()
+}
+
+// flushSummaries отправляет сохраненные итоги по порядку;
+// неотправленные остаются в файле до следующего подключения
+func (a *Agent) flushSummaries() {
+	a.summaryMu.Lock()
+	pending := a.pendingSummaries
+	a.pendingSummaries = nil
+	a.summaryMu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	sent := 0
+	for _, s := range pending {
+		if err := a.sendSummary(s); err != nil {
+			break
+		}
+		sent++
+	}
+
+	a.summaryMu.Lock()
+	defer a.summaryMu.Unlock()
+	a.pendingSummaries = append(pending[sent:len(pending):len(pending)], a.pendingSummaries...)
+	if len(a.pendingSummaries) > 0 {
+		log.Printf("%d usage summaries wait for connection", len(a.pendingSummaries))
+	}
+	a.saveState(pendingSummariesFile, a.pendingSummaries)
+}
@@ -0,0 +1,24 @@
+package core
+
+import (
+	"school_agent/internal/models"
+	"testing"
+)
+
+// Итоги дня без связи сохраняются на диск и переживают перезапуск
+func TestUsageSummariesSavedOffline(t *testing.T) {
+	a := offlineAgent(t)
+
+	a.queueSummary(models.UsageSummary{Date: "2024-09-02", User: "pupil"})
+	a.queueSummary(models.UsageSummary{Date: "2024-09-02", User: "teacher"})
+
+	restarted := offlineAgent(t)
+	restarted.cfg.StateDir = a.cfg.StateDir
+	if !restarted.loadState(pendingSummariesFile, &restarted.pendingSummaries) {
+		t.Fatal("pending summaries were not saved")
+	}
+	restarted.flushSummaries()
+	if got := restarted.pendingSummaries; len(got) != 2 || got[0].User != "pupil" || got[1].User != "teacher" {
+		t.Fatalf("pending summaries: %+v", got)
+	}
+}
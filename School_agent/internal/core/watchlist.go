@@ -0,0 +1,29 @@
+package core
+
+import (
+	"fmt"
+	"school_agent/internal/models"
+	"strings"
+)
+
+// onTreeAlert сообщает о процессе, запущенном запрещенным родителем (правило tree_rules)
+func (a *Agent) onTreeAlert(ruleID, program string, details *models.ProcessDetails) {
+	a.logMgr.AddEntry(models.LogEntry{
+		Username: a.currentUser,
+		LogType:  "alert",
+		Program:  program,
+		Action:   fmt.Sprintf("Process tree rule %s: started by %s", ruleID, strings.Join(details.Ancestry, " < ")),
+		Process:  details,
+		Category: a.categories.ForProcess(program, details.Identity),
+	})
+
+	a.queueAlert(map[string]interface{}{
+		"priority": "high",
+		"kind":     "process_tree",
+		"device":   a.cfg.Hostname,
+		"user":     a.currentUser,
+		"rule":     ruleID,
+		"program":  program,
+		"process":  details,
+	})
+}
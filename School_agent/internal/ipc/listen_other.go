@@ -0,0 +1,25 @@
+//go:build !windows
+
+package ipc
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Вне Windows вместо named pipe используется unix-сокет (разработка, тесты)
+func socketPath() string {
+	return filepath.Join(os.TempDir(), "SchoolAgentIPC.sock")
+}
+
+func listen() (net.Listener, error) {
+	path := socketPath()
+	os.Remove(path)
+	return net.Listen("unix", path)
+}
+
+// Dial подключается к агенту со стороны пользовательской сессии
+func Dial() (net.Conn, error) {
+	return net.Dial("unix", socketPath())
+}
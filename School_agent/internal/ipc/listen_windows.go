@@ -0,0 +1,24 @@
+package ipc
+
+import (
+	"net"
+	"school_agent/internal/config"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+)
+
+// pipeSecurity — SYSTEM и администраторы владеют каналом, интерактивные
+// пользователи (оболочка, помощник фокуса) могут читать и писать.
+// Кто именно пишет, сервер проверяет по процессу клиента (peerUser).
+const pipeSecurity = "D:P(A;;GA;;;SY)(A;;GA;;;BA)(A;;GRGW;;;IU)"
+
+func listen() (net.Listener, error) {
+	return winio.ListenPipe(config.PipeName, &winio.PipeConfig{SecurityDescriptor: pipeSecurity})
+}
+
+// Dial подключается к агенту со стороны пользовательской сессии
+func Dial() (net.Conn, error) {
+	timeout := 5 * time.Second
+	return winio.DialPipe(config.PipeName, &timeout)
+}
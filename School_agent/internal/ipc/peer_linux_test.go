@@ -0,0 +1,39 @@
+package ipc
+
+import (
+	"net"
+	"os/user"
+	"path/filepath"
+	"testing"
+)
+
+func TestPeerUserFromSocket(t *testing.T) {
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "ipc.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	client, err := net.Dial("unix", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	got, err := peerUser(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	me, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != me.Username {
+		t.Errorf("peer user = %q, want %q", got, me.Username)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !linux
+
+package ipc
+
+import (
+	"errors"
+	"net"
+)
+
+// На остальных системах отправителя не проверить: соединения отклоняются
+func peerUser(c net.Conn) (string, error) {
+	return "", errors.New("peer credentials are not supported")
+}
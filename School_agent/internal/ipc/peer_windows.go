@@ -0,0 +1,40 @@
+package ipc
+
+import (
+	"errors"
+	"net"
+
+	"golang.org/x/sys/windows"
+)
+
+// peerUser возвращает учетную запись процесса на другом конце канала
+// (имя без домена, как у активного пользователя агента)
+func peerUser(c net.Conn) (string, error) {
+	f, ok := c.(interface{ Fd() uintptr })
+	if !ok {
+		return "", errors.New("not a named pipe")
+	}
+	var pid uint32
+	if err := windows.GetNamedPipeClientProcessId(windows.Handle(f.Fd()), &pid); err != nil {
+		return "", err
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h)
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(h, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+
+	tu, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+	account, _, _, err := tu.User.Sid.LookupAccount("")
+	return account, err
+}
@@ -2,24 +2,63 @@ package ipc
 
 import (
 	"encoding/json"
+	"log"
 	"net"
-	"school_agent/internal/config"
 	"school_agent/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CmdSubscribe — оболочка держит соединение открытым и получает уведомления агента
+const CmdSubscribe = "subscribe"
 
-	"github.com/Microsoft/go-winio"
+const (
+	// Очередь уведомлений одного подписчика; при переполнении новые отбрасываются
+	subscriberQueue = 16
+	// Подписчик, не принявший сообщение за это время, отключается
+	writeTimeout = 5 * time.Second
 )
 
+// subscriber — подписанная оболочка. В соединение пишет только ее горутина
+// writeLoop, поэтому Notify не ждет медленного клиента.
+type subscriber struct {
+	conn net.Conn
+	user string
+	out  chan models.IPCMessage
+}
+
+// Handler отвечает на запрос оболочки по тому же соединению.
+// req.User — проверенная учетная запись отправителя, а не поле запроса.
+type Handler func(req models.IPCMessage) models.IPCMessage
+
 type Server struct {
-	msgChan chan models.IPCMessage
+	msgChan  chan models.IPCMessage
+	handlers map[string]Handler
+	// peer определяет пользователя процесса на другом конце соединения
+	peer func(net.Conn) (string, error)
+
+	mu          sync.Mutex
+	subscribers map[net.Conn]*subscriber
 }
 
 func New(msgChan chan models.IPCMessage) *Server {
-	return &Server{msgChan: msgChan}
+	return &Server{
+		msgChan:     msgChan,
+		handlers:    make(map[string]Handler),
+		peer:        peerUser,
+		subscribers: make(map[net.Conn]*subscriber),
+	}
+}
+
+// Handle регистрирует обработчик запроса; регистрировать до Start
+func (s *Server) Handle(cmd string, h Handler) {
+	s.handlers[cmd] = h
 }
 
 func (s *Server) Start() {
 	go func() {
-		l, err := winio.ListenPipe(config.PipeName, nil)
+		l, err := listen()
 		if err != nil {
 			return
 		}
@@ -35,11 +74,92 @@ func (s *Server) Start() {
 	}()
 }
 
+// handleConn обслуживает соединение, пока клиент его не закроет: запросы
+// с обработчиком получают ответ, subscribe включает уведомления, остальное
+// уходит в msgChan
 func (s *Server) handleConn(c net.Conn) {
 	defer c.Close()
+	// Канал открыт всем вошедшим пользователям, поэтому имени в запросе
+	// не верим: сообщения подписываются учетной записью отправителя
+	user, err := s.peer(c)
+	if err != nil {
+		log.Printf("IPC client identity check failed, closing connection: %v", err)
+		return
+	}
 	decoder := json.NewDecoder(c)
-	var msg models.IPCMessage
-	if err := decoder.Decode(&msg); err == nil {
-		s.msgChan <- msg
+	encoder := json.NewEncoder(c)
+	var sub *subscriber
+
+	for {
+		var msg models.IPCMessage
+		if err := decoder.Decode(&msg); err != nil {
+			break
+		}
+		msg.User = user
+
+		switch h, ok := s.handlers[msg.Command]; {
+		case ok && sub != nil:
+			sub.out <- h(msg)
+		case ok:
+			encoder.Encode(h(msg))
+		case msg.Command == CmdSubscribe && sub == nil:
+			sub = &subscriber{conn: c, user: user, out: make(chan models.IPCMessage, subscriberQueue)}
+			go sub.writeLoop()
+			s.mu.Lock()
+			s.subscribers[c] = sub
+			s.mu.Unlock()
+		case msg.Command == CmdSubscribe:
+		default:
+			s.msgChan <- msg
+		}
 	}
-}
\ No newline at end of file
+
+	if sub != nil {
+		// Notify отправляет только под s.mu, после удаления очередь можно закрыть
+		s.mu.Lock()
+		delete(s.subscribers, c)
+		s.mu.Unlock()
+		close(sub.out)
+	}
+}
+
+// writeLoop пишет уведомления и ответы подписчику. Запись, не завершенная
+// за writeTimeout, закрывает соединение; handleConn после этого удаляет
+// подписчика, а оставшаяся очередь вычитывается впустую.
+func (sub *subscriber) writeLoop() {
+	encoder := json.NewEncoder(sub.conn)
+	failed := false
+	for msg := range sub.out {
+		if failed {
+			continue
+		}
+		sub.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := encoder.Encode(msg); err != nil {
+			log.Printf("IPC subscriber write failed, disconnecting: %v", err)
+			sub.conn.Close()
+			failed = true
+		}
+	}
+}
+
+// Notify ставит сообщение в очередь оболочкам пользователя user и не ждет
+// записи. Оболочки других пользователей (другие сеансы на терминальном
+// сервере) его не получают. Возвращает false, если ни одна оболочка его не приняла.
+func (s *Server) Notify(user string, msg models.IPCMessage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := false
+	for _, sub := range s.subscribers {
+		if !strings.EqualFold(sub.user, user) {
+			continue
+		}
+		select {
+		case sub.out <- msg:
+			sent = true
+		default:
+			log.Printf("IPC subscriber queue is full, %s dropped", msg.Command)
+		}
+	}
+	return sent
+}
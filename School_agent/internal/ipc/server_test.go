@@ -0,0 +1,210 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"school_agent/internal/models"
+	"testing"
+	"time"
+)
+
+// newTestServer — сервер, для которого все клиенты net.Pipe — ученик pupil
+func newTestServer(ch chan models.IPCMessage) *Server {
+	s := New(ch)
+	s.peer = func(net.Conn) (string, error) { return "pupil", nil }
+	return s
+}
+
+func TestHandlerServesManyRequestsPerConnection(t *testing.T) {
+	s := newTestServer(make(chan models.IPCMessage, 1))
+	calls := 0
+	s.Handle("foreground", func(req models.IPCMessage) models.IPCMessage {
+		calls++
+		return models.IPCMessage{Command: "ok", Program: req.Program}
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.handleConn(server)
+
+	enc, dec := json.NewEncoder(client), json.NewDecoder(client)
+	for _, app := range []string{"a.exe", "b.exe", "c.exe"} {
+		if err := enc.Encode(models.IPCMessage{Command: "foreground", Program: app}); err != nil {
+			t.Fatal(err)
+		}
+		var reply models.IPCMessage
+		if err := dec.Decode(&reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Program != app {
+			t.Errorf("reply = %+v, want program %s", reply, app)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+}
+
+func TestUnhandledMessagesGoToChannel(t *testing.T) {
+	ch := make(chan models.IPCMessage, 2)
+	s := newTestServer(ch)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.handleConn(server)
+
+	enc := json.NewEncoder(client)
+	enc.Encode(models.IPCMessage{Command: "log", Program: "shell", Action: "one"})
+	enc.Encode(models.IPCMessage{Command: "log", Program: "shell", Action: "two"})
+
+	for _, want := range []string{"one", "two"} {
+		select {
+		case msg := <-ch:
+			if msg.Action != want {
+				t.Errorf("got %q, want %q", msg.Action, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %q not delivered", want)
+		}
+	}
+}
+
+// Имя пользователя в запросе не учитывается: обработчик получает
+// учетную запись процесса клиента
+func TestRequestUserIsCaller(t *testing.T) {
+	ch := make(chan models.IPCMessage, 1)
+	s := newTestServer(ch)
+	s.Handle("get_quota", func(req models.IPCMessage) models.IPCMessage {
+		return models.IPCMessage{Command: "quota", User: req.User}
+	})
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.handleConn(server)
+
+	enc, dec := json.NewEncoder(client), json.NewDecoder(client)
+	enc.Encode(models.IPCMessage{Command: "get_quota", User: "teacher"})
+	var reply models.IPCMessage
+	if err := dec.Decode(&reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.User != "pupil" {
+		t.Errorf("handler saw user %q, want pupil", reply.User)
+	}
+
+	enc.Encode(models.IPCMessage{Command: "log", User: "teacher", Action: "fake"})
+	select {
+	case msg := <-ch:
+		if msg.User != "pupil" {
+			t.Errorf("log from user %q, want pupil", msg.User)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("log message not delivered")
+	}
+}
+
+func TestUnknownCallerIsDisconnected(t *testing.T) {
+	ch := make(chan models.IPCMessage, 1)
+	s := New(ch)
+	s.peer = func(net.Conn) (string, error) { return "", errors.New("no credentials") }
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go s.handleConn(server)
+
+	client.SetDeadline(time.Now().Add(time.Second))
+	if err := json.NewEncoder(client).Encode(models.IPCMessage{Command: "log"}); err == nil {
+		t.Fatal("server accepted a message from an unverified client")
+	}
+	if len(ch) != 0 {
+		t.Fatal("message from an unverified client delivered")
+	}
+}
+
+// subscribe подключает оболочку и ждет, пока сервер ее зарегистрирует
+func subscribe(t *testing.T, s *Server) net.Conn {
+	t.Helper()
+	client, server := net.Pipe()
+	go s.handleConn(server)
+	if err := json.NewEncoder(client).Encode(models.IPCMessage{Command: CmdSubscribe}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		s.mu.Lock()
+		n := len(s.subscribers)
+		s.mu.Unlock()
+		if n > 0 {
+			return client
+		}
+		if i == 100 {
+			t.Fatal("subscriber not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNotifyDeliversToSubscriber(t *testing.T) {
+	s := newTestServer(make(chan models.IPCMessage))
+	client := subscribe(t, s)
+	defer client.Close()
+
+	if !s.Notify("Pupil", models.IPCMessage{Command: "warning", Program: "game.exe", Countdown: 30}) {
+		t.Fatal("Notify reported no subscribers")
+	}
+	var msg models.IPCMessage
+	if err := json.NewDecoder(client).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Program != "game.exe" || msg.Countdown != 30 {
+		t.Errorf("got %+v", msg)
+	}
+}
+
+func TestNotifyDoesNotWaitForSlowSubscriber(t *testing.T) {
+	s := newTestServer(make(chan models.IPCMessage))
+	client := subscribe(t, s)
+	defer client.Close()
+
+	// Клиент ничего не читает: net.Pipe не буферизует, запись висит
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberQueue*2; i++ {
+			s.Notify("pupil", models.IPCMessage{Command: "warning"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Notify blocked on a subscriber that does not read")
+	}
+}
+
+func TestNotifyWithoutSubscribers(t *testing.T) {
+	s := newTestServer(make(chan models.IPCMessage))
+	if s.Notify("pupil", models.IPCMessage{Command: "warning"}) {
+		t.Error("Notify reported delivery without subscribers")
+	}
+}
+
+// Предупреждение получает только оболочка пользователя, которому оно адресовано
+func TestNotifySkipsOtherUsers(t *testing.T) {
+	s := newTestServer(make(chan models.IPCMessage))
+	client := subscribe(t, s)
+	defer client.Close()
+
+	if s.Notify("teacher", models.IPCMessage{Command: "warning", Program: "game.exe"}) {
+		t.Fatal("warning for another user delivered")
+	}
+	if !s.Notify("pupil", models.IPCMessage{Command: "warning", Program: "word.exe"}) {
+		t.Fatal("warning for the subscriber not delivered")
+	}
+	var msg models.IPCMessage
+	if err := json.NewDecoder(client).Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Program != "word.exe" {
+		t.Errorf("got %+v, want only the pupil's warning", msg)
+	}
+}
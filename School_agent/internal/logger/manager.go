@@ -32,17 +32,24 @@ func (m *Manager) Start() {
 }
 
 func (m *Manager) Add(user, lType, prog, action string) {
-	if user == "" {
-		user = "system"
+	m.AddEntry(models.LogEntry{
+		Username: user,
+		LogType:  lType,
+		Program:  prog,
+		Action:   action,
+	})
+}
+
+// AddEntry ставит в очередь запись с дополнительными полями
+func (m *Manager) AddEntry(entry models.LogEntry) {
+	if entry.Username == "" {
+		entry.Username = "system"
 	}
-	m.queue <- models.LogEntry{
-		Username:   user,
-		DeviceName: m.hostname,
-		Timestamp:  time.Now(),
-		LogType:    lType,
-		Program:    prog,
-		Action:     action,
+	entry.DeviceName = m.hostname
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
 	}
+	m.queue <- entry
 }
 
 func (m *Manager) writeToFile(entry models.LogEntry) {
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// BrowserVisit — посещение страницы из истории браузера (V2)
+type BrowserVisit struct {
+	Browser string    `json:"browser"`
+	Profile string    `json:"profile,omitempty"`
+	URL     string    `json:"url"`
+	Title   string    `json:"title,omitempty"`
+	Time    time.Time `json:"time"`
+
+	// Посещение из истории: id в базе браузера, тип перехода
+	// (typed, link, reload, redirect...), страница-источник
+	VisitID    int64  `json:"visit_id,omitempty"`
+	Transition string `json:"transition,omitempty"`
+	Referrer   string `json:"referrer,omitempty"`
+	// Сколько страница была открыта; пишет только Chromium
+	DurationSec float64 `json:"duration_sec,omitempty"`
+
+	// Поисковый запрос, если страница — выдача поисковика
+	SearchEngine string `json:"search_engine,omitempty"`
+	Query        string `json:"query,omitempty"`
+}
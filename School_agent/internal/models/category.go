@@ -0,0 +1,35 @@
+package models
+
+// Категории приложений и сайтов по умолчанию
+const (
+	CategoryGames       = "games"
+	CategorySocial      = "social"
+	CategoryMessaging   = "messaging"
+	CategoryOffice      = "office"
+	CategoryProgramming = "programming"
+	CategoryVideo       = "video"
+	CategoryMusic       = "music"
+	CategoryGraphics    = "graphics"
+	CategoryEducation   = "education"
+	CategorySearch      = "search"
+	CategoryBrowser     = "browser"
+)
+
+// Categories — сопоставление программ и сайтов категориям. Встроенный
+// справочник агента дополняется и переопределяется из конфига и командой
+// SET_CATEGORIES. Ключи без учета регистра; домен покрывает и поддомены.
+type Categories struct {
+	Version string            `json:"version"`
+	Exes    map[string]string `json:"exes"`    // имя exe -> категория
+	Hashes  map[string]string `json:"hashes"`  // SHA-256 exe -> категория
+	Domains map[string]string `json:"domains"` // домен -> категория
+}
+
+// CategoryUsage — суммарное время приложений одной категории за день
+type CategoryUsage struct {
+	Category string   `json:"category"`
+	TotalSec float64  `json:"total_sec"`
+	FocusSec float64  `json:"focus_sec"`
+	Launches int      `json:"launches"`
+	Apps     []string `json:"apps"`
+}
@@ -0,0 +1,31 @@
+package models
+
+// Действия политики для запрещенных процессов
+const (
+	PolicyLog       = "log"       // только записать в лог
+	PolicyWarn      = "warn"      // предупредить пользователя через оболочку
+	PolicyTerminate = "terminate" // предупредить и завершить процесс после отсчета
+)
+
+type PolicyRule struct {
+	ID     string      `json:"id"`
+	Match  []WatchRule `json:"match"`
+	Action string      `json:"action"`
+	// Сколько секунд показывать предупреждение перед завершением
+	WarnSeconds int    `json:"warn_seconds"`
+	Message     string `json:"message"`
+	// Когда правило действует; пусто — всегда
+	Schedule []TimeRange `json:"schedule"`
+	Days     []string    `json:"days"` // "mon".."sun"
+	// Группы пользователей, на которых правило не распространяется
+	ExemptGroups []string `json:"exempt_groups"`
+}
+
+// Policy — версионированный набор правил блокировки. Приходит из конфига
+// или командой SET_POLICY от сервера.
+type Policy struct {
+	Version string       `json:"version"`
+	Rules   []PolicyRule `json:"rules"`
+	// Группа -> имена пользователей
+	UserGroups map[string][]string `json:"user_groups"`
+}
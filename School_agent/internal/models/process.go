@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ExeIdentity — хеш и встроенные метаданные исполняемого файла: ресурс
+// версии на Windows, build-id и данные сборки Go в ELF
+type ExeIdentity struct {
+	SHA256       string `json:"sha256,omitempty"`
+	OriginalName string `json:"original_name,omitempty"`
+	ProductName  string `json:"product_name,omitempty"`
+	Company      string `json:"company,omitempty"`
+	FileVersion  string `json:"file_version,omitempty"`
+	BuildID      string `json:"build_id,omitempty"`
+}
+
+// ProcessDetails — метаданные процесса в событиях Opened/Closed
+type ProcessDetails struct {
+	PID         int32  `json:"pid"`
+	Exe         string `json:"exe,omitempty"`
+	CommandLine string `json:"command_line,omitempty"`
+	ParentPID   int32  `json:"parent_pid,omitempty"`
+	ParentName  string `json:"parent_name,omitempty"`
+	// Имена предков от родителя к корню
+	Ancestry  []string  `json:"ancestry,omitempty"`
+	Owner     string    `json:"owner,omitempty"`
+	SessionID uint32    `json:"session_id"`
+	StartTime time.Time `json:"start_time"`
+
+	Identity *ExeIdentity `json:"identity,omitempty"`
+	// Имя процесса не совпадает с исходным именем файла: программу переименовали
+	Renamed bool `json:"renamed,omitempty"`
+
+	// Только в событии Closed
+	EndTime     *time.Time `json:"end_time,omitempty"`
+	DurationSec float64    `json:"duration_sec,omitempty"`
+}
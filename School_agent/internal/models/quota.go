@@ -0,0 +1,43 @@
+package models
+
+// AppQuota — дневной лимит времени на приложение или группу приложений
+// ("игры": steam.exe, *minecraft*). Время идет, пока запущено хотя бы одно
+// подходящее приложение; две игры одновременно не считаются дважды.
+// Match — те же правила, что в списке отслеживания; sha256, product и
+// original_name срабатывают, только если включен сбор метаданных exe.
+//
+// Время считается только по процессам, которые отслеживает монитор
+// процессов. Приложения лимита должны подходить под правила watchlist,
+// а для лимитов по категории нужен watchlist с all_user_processes:
+// иначе запуски не видны и лимит не расходуется.
+type AppQuota struct {
+	ID    string      `json:"id"`
+	Match []WatchRule `json:"match"`
+	// Категория приложений ("games"); дополняет Match
+	Category string `json:"category"`
+	LimitMin int    `json:"limit_min"`
+	// Завершать приложения, когда лимит исчерпан, через GraceSec после предупреждения
+	Terminate bool   `json:"terminate"`
+	GraceSec  int    `json:"grace_sec"`
+	Message   string `json:"message"`
+	// Пользователи, на которых действует лимит; пусто — все
+	Users []string `json:"users"`
+}
+
+// Quotas — версионированный набор дневных лимитов. Приходит из конфига
+// или командой SET_QUOTAS от сервера.
+type Quotas struct {
+	Version string `json:"version"`
+	// Время суток, когда лимиты обнуляются
+	ResetAt Clock      `json:"reset_at"`
+	Rules   []AppQuota `json:"rules"`
+}
+
+// QuotaStatus — остаток лимита пользователя в текущем периоде
+type QuotaStatus struct {
+	ID           string  `json:"id"`
+	LimitSec     float64 `json:"limit_sec"`
+	UsedSec      float64 `json:"used_sec"`
+	RemainingSec float64 `json:"remaining_sec"`
+	Exceeded     bool    `json:"exceeded"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// Метрики для порогов ресурсных алертов
+const (
+	MetricCPU      = "cpu"       // % одного ядра, 0..100*число ядер
+	MetricMemoryMB = "memory_mb" // RSS
+	MetricDiskKBps = "disk_kbps" // чтение + запись
+	MetricThreads  = "threads"
+)
+
+// ResourceAlertRule — например, CPU > 80% одного ядра дольше 10 минут у неизвестного exe
+type ResourceAlertRule struct {
+	ID     string  `json:"id"`
+	Metric string  `json:"metric"`
+	Above  float64 `json:"above"`
+	ForSec int     `json:"for_sec"`
+	// Только для процессов, не попадающих в список отслеживаемых
+	UnknownOnly bool `json:"unknown_only"`
+}
+
+// ResourceStats — текущие значения и скользящая статистика процесса
+type ResourceStats struct {
+	PID       int32     `json:"pid"`
+	Name      string    `json:"name"`
+	Exe       string    `json:"exe,omitempty"`
+	StartTime time.Time `json:"start_time"`
+
+	CPU      float64 `json:"cpu"` // % одного ядра
+	CPUAvg   float64 `json:"cpu_avg"`
+	CPUMax   float64 `json:"cpu_max"`
+	MemoryMB float64 `json:"memory_mb"`
+	MemMaxMB float64 `json:"memory_max_mb"`
+	DiskKBps float64 `json:"disk_kbps"`
+	Threads  int32   `json:"threads"`
+	Samples  int     `json:"samples"`
+}
+
+// ResourceAlert — срабатывание порога, отправляется серверу сразу
+type ResourceAlert struct {
+	Rule      string        `json:"rule"`
+	Metric    string        `json:"metric"`
+	Value     float64       `json:"value"`
+	Threshold float64       `json:"threshold"`
+	ForSec    int           `json:"for_sec"`
+	Process   ResourceStats `json:"process"`
+}
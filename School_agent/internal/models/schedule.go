@@ -0,0 +1,83 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TimeRange — интервал локального времени суток в формате "08:00-15:00".
+// Если конец меньше начала, интервал переходит через полночь.
+type TimeRange struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+func (r TimeRange) Contains(t time.Time) bool {
+	h, m, s := t.Clock()
+	now := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second
+	if r.Start <= r.End {
+		return now >= r.Start && now < r.End
+	}
+	return now >= r.Start || now < r.End
+}
+
+func (r TimeRange) String() string {
+	return fmt.Sprintf("%s-%s", formatClock(r.Start), formatClock(r.End))
+}
+
+func (r *TimeRange) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var sh, sm, eh, em int
+	if _, err := fmt.Sscanf(s, "%d:%d-%d:%d", &sh, &sm, &eh, &em); err != nil {
+		return fmt.Errorf("invalid time range %q: %v", s, err)
+	}
+	r.Start = time.Duration(sh)*time.Hour + time.Duration(sm)*time.Minute
+	r.End = time.Duration(eh)*time.Hour + time.Duration(em)*time.Minute
+	return nil
+}
+
+func (r TimeRange) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// Clock — время суток в формате "04:00"
+type Clock time.Duration
+
+// Last возвращает последний момент с этим временем суток не позже t
+func (c Clock) Last(t time.Time) time.Time {
+	y, m, d := t.Date()
+	at := time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(time.Duration(c))
+	if at.After(t) {
+		at = at.AddDate(0, 0, -1)
+	}
+	return at
+}
+
+func (c Clock) String() string {
+	return formatClock(time.Duration(c))
+}
+
+func (c *Clock) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return fmt.Errorf("invalid time of day %q", s)
+	}
+	*c = Clock(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute)
+	return nil
+}
+
+func (c Clock) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
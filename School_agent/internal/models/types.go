@@ -9,6 +9,13 @@ type LogEntry struct {
 	LogType    string    `json:"log_type"` 
 	Program    string    `json:"program"`
 	Action     string    `json:"action"`
+
+	// Метаданные процесса (только для log_type process, начиная с протокола V2)
+	Process *ProcessDetails `json:"process,omitempty"`
+	// Категория программы или сайта (V2)
+	Category string `json:"category,omitempty"`
+	// Посещение страницы (только для log_type browser, V2)
+	Browser *BrowserVisit `json:"browser,omitempty"`
 }
 
 type IPCMessage struct {
@@ -16,8 +23,47 @@ type IPCMessage struct {
 	User    string `json:"user,omitempty"`
 	Program string `json:"program,omitempty"`
 	Action  string `json:"action,omitempty"`
+
+	// warning: текст предупреждения и отсчет до завершения программы
+	Message   string `json:"message,omitempty"`
+	Countdown int    `json:"countdown,omitempty"`
+
+	// quota: ответ на get_quota — остатки дневных лимитов
+	Quotas []QuotaStatus `json:"quotas,omitempty"`
+
+	// foreground: активное окно в сессии пользователя (Program — имя exe,
+	// пусто — фокуса нет)
+	PID   int32  `json:"pid,omitempty"`
+	Title string `json:"title,omitempty"`
 }
 
 type WSCommand struct {
 	Type string `json:"type"`
+
+	// PROTOCOL: версия и возможности, выбранные сервером
+	Version      int      `json:"version,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// LOGS_ACK: подтверждение чанка логов
+	BatchID string `json:"batch_id,omitempty"`
+	Seq     int    `json:"seq,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	// SET_WATCHLIST: новый список отслеживаемых процессов
+	Watchlist *Watchlist `json:"watchlist,omitempty"`
+
+	// SET_POLICY: новые правила блокировки процессов
+	Policy *Policy `json:"policy,omitempty"`
+
+	// SET_QUOTAS: новые дневные лимиты времени
+	Quotas *Quotas `json:"quotas,omitempty"`
+
+	// SET_CATEGORIES: переопределения справочника категорий
+	Categories *Categories `json:"categories,omitempty"`
+
+	// SET_URL_RULES: новый фильтр событий браузера
+	URLRules *URLRules `json:"url_rules,omitempty"`
+
+	// GET_USAGE: дата сводки (2006-01-02), пусто — сегодня
+	Date string `json:"date,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,44 @@
+package models
+
+// Действия правил фильтра адресов
+const (
+	URLInclude = "include"
+	URLExclude = "exclude"
+)
+
+// URLRule — условие на адрес посещенной страницы. Заданные поля
+// проверяются все вместе, внутри списка достаточно одного совпадения.
+type URLRule struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	// Схемы без "://": https, chrome-extension
+	Schemes []string `json:"schemes"`
+	// Домен совпадает и со своими поддоменами: youtube.com ловит m.youtube.com
+	Domains []string `json:"domains"`
+	// Префикс пути или маска с * и ?, например "/watch" или "/*/edit"
+	Paths []string `json:"paths"`
+	// Длина адреса целиком; 0 — без ограничения
+	MinLength int `json:"min_length"`
+	MaxLength int `json:"max_length"`
+}
+
+// URLRules — версионированный фильтр событий браузера: правила проверяются
+// по порядку, действует первое совпавшее. Приходит из конфига или
+// командой SET_URL_RULES от сервера.
+type URLRules struct {
+	Version string `json:"version"`
+	// Действие, если ни одно правило не совпало; пусто — include
+	Default string    `json:"default"`
+	Rules   []URLRule `json:"rules"`
+	// Проверочный набор: считается рядом с действующим фильтром только для
+	// url_rules_report, действующий фильтр не меняет и не сохраняется
+	DryRun bool `json:"dry_run"`
+}
+
+// URLRuleReport — сколько адресов совпало с правилом и примеры
+type URLRuleReport struct {
+	ID      string   `json:"id"`
+	Action  string   `json:"action"`
+	Matched int      `json:"matched"`
+	Samples []string `json:"samples,omitempty"`
+}
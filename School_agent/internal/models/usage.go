@@ -0,0 +1,22 @@
+package models
+
+// AppUsage — использование одного приложения одним пользователем за день
+type AppUsage struct {
+	App        string  `json:"app"`
+	TotalSec   float64 `json:"total_sec"`
+	Launches   int     `json:"launches"`
+	LongestSec float64 `json:"longest_sec"`
+	// Сколько времени окно приложения было в фокусе
+	FocusSec float64 `json:"focus_sec"`
+	Category string  `json:"category,omitempty"`
+}
+
+// UsageSummary — дневная сводка пользователя, сообщение usage_summary
+type UsageSummary struct {
+	Date   string     `json:"date"` // 2006-01-02, локальное время
+	User   string     `json:"user"`
+	Device string     `json:"device"`
+	Apps   []AppUsage `json:"apps"`
+	// Время по категориям; приложения без категории сюда не входят
+	Categories []CategoryUsage `json:"categories,omitempty"`
+}
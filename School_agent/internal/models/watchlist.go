@@ -0,0 +1,47 @@
+package models
+
+// Типы правил списка отслеживаемых процессов. Правила по имени (name, glob,
+// regex) сверяются и с исходным именем файла из ресурса версии, поэтому
+// переименованная копия программы тоже подходит.
+const (
+	RuleName   = "name"   // точное имя exe без учета регистра
+	RuleGlob   = "glob"   // маска имени exe без учета регистра, например "*craft*.exe"
+	RuleRegex  = "regex"  // регулярное выражение по имени exe
+	RulePath   = "path"   // префикс полного пути к exe
+	RuleSHA256 = "sha256" // хеш исполняемого файла
+	// Метаданные исполняемого файла: ловят переименованные программы
+	RuleProduct      = "product"       // маска названия продукта, например "Steam*"
+	RuleOriginalName = "original_name" // исходное имя файла из ресурса версии
+)
+
+type WatchRule struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+}
+
+// Действия правил дерева процессов
+const (
+	TreeAlert  = "alert"  // срочный алерт на сервер
+	TreeIgnore = "ignore" // не сообщать о процессе
+)
+
+// TreeRule — правило по родителю процесса, например
+// "powershell.exe, запущенный браузером" или "git.exe из Code.exe".
+type TreeRule struct {
+	ID      string      `json:"id"`
+	Process []WatchRule `json:"process"`
+	Parent  []WatchRule `json:"parent"`
+	// Проверять всех предков, а не только непосредственного родителя
+	AnyAncestor bool   `json:"any_ancestor"`
+	Action      string `json:"action"`
+}
+
+// Watchlist — версионированный набор правил. Приходит из конфига
+// или командой SET_WATCHLIST от сервера.
+type Watchlist struct {
+	Version string `json:"version"`
+	// AllUserProcesses — отслеживать все процессы пользователей (не SYSTEM/служб)
+	AllUserProcesses bool        `json:"all_user_processes"`
+	Rules            []WatchRule `json:"rules"`
+	TreeRules        []TreeRule  `json:"tree_rules"`
+}
@@ -1,30 +1,67 @@
 package monitor
 
 import (
-	"database/sql"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
 	"strings"
+	"sync"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type BrowserMonitor struct {
-	lastCheck map[string]time.Time
-	callback  func(browser, url string)
-	username  string
+	callback func(visit models.BrowserVisit)
+	username string
+	browsers []config.BrowserDef
+	redact   []*regexp.Regexp
+
+	// Курсоры чтения истории, сохраняются в каталоге состояния
+	cursors      map[string]browserCursor
+	cursorsPath  string
+	cursorsDirty bool
+	backfill     time.Duration
+
+	// Каталог агента для копий баз истории
+	snapshotRoot string
+
+	mu     sync.Mutex
+	filter *urlFilter
+	// Правила в режиме dry run: проверяются параллельно с filter и только
+	// заполняют свой отчет; nil — проверки нет
+	shadow *urlFilter
 }
 
-func NewBrowserMonitor(username string, callback func(browser, url string)) *BrowserMonitor {
-	return &BrowserMonitor{
-		lastCheck: make(map[string]time.Time),
-		callback:  callback,
-		username:  username,
+func NewBrowserMonitor(cfg config.BrowserConfig, stateDir, username string, callback func(visit models.BrowserVisit)) *BrowserMonitor {
+	bm := &BrowserMonitor{
+		callback:     callback,
+		username:     username,
+		browsers:     browserTable(cfg.Browsers),
+		cursors:      make(map[string]browserCursor),
+		cursorsPath:  filepath.Join(stateDir, cursorsFile),
+		backfill:     time.Duration(cfg.BackfillMin) * time.Minute,
+		snapshotRoot: filepath.Join(stateDir, snapshotDirName),
 	}
+	if bm.backfill <= 0 {
+		bm.backfill = defaultBackfill
+	}
+	bm.loadCursors()
+	cleanSnapshots(bm.snapshotRoot)
+	bm.filter, _ = compileURLRules(models.URLRules{})
+	for _, pattern := range cfg.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Bad browser redact pattern %q: %v", pattern, err)
+			continue
+		}
+		bm.redact = append(bm.redact, re)
+	}
+	return bm
 }
 
 func (bm *BrowserMonitor) Start() {
@@ -47,9 +84,11 @@ func (bm *BrowserMonitor) checkBrowsers() {
 
 	cleanUsername := bm.cleanUsername(bm.username)
 	
-	bm.checkChrome(cleanUsername)
-	bm.checkEdge(cleanUsername)
-	bm.checkFirefox(cleanUsername)
+	home := fmt.Sprintf("C:\\Users\\%s", cleanUsername)
+	for _, def := range bm.browsers {
+		bm.checkBrowser(cleanUsername, def, filepath.Join(home, filepath.FromSlash(def.Root)))
+	}
+	bm.saveCursors()
 }
 
 func (bm *BrowserMonitor) cleanUsername(username string) string {
@@ -59,227 +98,258 @@ func (bm *BrowserMonitor) cleanUsername(username string) string {
 	return username
 }
 
-func (bm *BrowserMonitor) checkChrome(username string) {
-	historyPath := fmt.Sprintf("C:\\Users\\%s\\AppData\\Local\\Google\\Chrome\\User Data\\Default\\History", username)
-	bm.readChromeHistory(historyPath, "Chrome")
-}
-
-func (bm *BrowserMonitor) checkEdge(username string) {
-	historyPath := fmt.Sprintf("C:\\Users\\%s\\AppData\\Local\\Microsoft\\Edge\\User Data\\Default\\History", username)
-	bm.readChromeHistory(historyPath, "Edge")
-}
-
-func (bm *BrowserMonitor) checkFirefox(username string) {
-	profilesPath := fmt.Sprintf("C:\\Users\\%s\\AppData\\Roaming\\Mozilla\\Firefox\\Profiles", username)
-	
-	entries, err := os.ReadDir(profilesPath)
-	if err != nil {
+func (bm *BrowserMonitor) checkBrowser(user string, def config.BrowserDef, root string) {
+	if _, err := os.Stat(root); err != nil {
 		return
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() && strings.HasSuffix(entry.Name(), ".default-release") {
-			historyPath := filepath.Join(profilesPath, entry.Name(), "places.sqlite")
-			bm.readFirefoxHistory(historyPath)
-			break
+	switch def.Format {
+	case config.BrowserChromium:
+		for _, profile := range chromiumProfiles(root) {
+			bm.readChromeHistory(filepath.Join(profile.Dir, "History"), def.Name, profile, cursorKey(user, def.Name, profile))
+		}
+	case config.BrowserFirefox:
+		for _, profile := range firefoxProfiles(root) {
+			bm.readFirefoxHistory(filepath.Join(profile.Dir, "places.sqlite"), def.Name, profile, cursorKey(user, def.Name, profile))
 		}
 	}
 }
 
-func (bm *BrowserMonitor) readChromeHistory(historyPath, browser string) {
-	if _, err := os.Stat(historyPath); os.IsNotExist(err) {
-		return
-	}
-
-	tempPath := historyPath + ".tmp"
-	if err := bm.copyFile(historyPath, tempPath); err != nil {
-		return
-	}
-	defer os.Remove(tempPath)
-
-	db, err := sql.Open("sqlite3", tempPath)
+func (bm *BrowserMonitor) readChromeHistory(historyPath, browser string, profile browserProfile, key string) {
+	db, cleanup, err := bm.openSnapshot(historyPath)
 	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot snapshot %s history: %v", browser, err)
+		}
 		return
 	}
-	defer db.Close()
+	defer cleanup()
 
-	lastCheck := bm.lastCheck[browser]
-	if lastCheck.IsZero() {
-		lastCheck = time.Now().Add(-5 * time.Minute)
+	cursor := bm.cursor(key)
+	now := time.Now()
+
+	// Поисковый запрос, который Chromium сам связал с адресом
+	term := "''"
+	if chromiumHasSearchTerms(db) {
+		term = "COALESCE((SELECT k.term FROM keyword_search_terms k WHERE k.url_id = u.id LIMIT 1), '')"
 	}
 
+	// Каждая строка visits — отдельное посещение; urls хранит только последнее
 	query := `
-		SELECT url, title, last_visit_time 
-		FROM urls 
-		WHERE last_visit_time > ? 
-		ORDER BY last_visit_time DESC 
-		LIMIT 50
+		SELECT v.id, u.url, COALESCE(u.title, ''), v.visit_time, v.transition,
+		       v.visit_duration, COALESCE(ref.url, ''), ` + term + `
+		FROM visits v
+		JOIN urls u ON u.id = v.url
+		LEFT JOIN visits fv ON fv.id = v.from_visit
+		LEFT JOIN urls ref ON ref.id = fv.url
 	`
 
-	chromeEpoch := time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)
-	lastCheckMicro := lastCheck.Sub(chromeEpoch).Microseconds()
-
-	rows, err := db.Query(query, lastCheckMicro)
+	visits, err := queryChromeVisits(db, query+`
+		WHERE v.visit_time >= ?
+		ORDER BY v.visit_time, v.id
+		LIMIT ?`, toChromeTime(cursor.Time), maxVisitsPerPoll)
 	if err != nil {
 		return
 	}
-	defer rows.Close()
+	// Посещения, открытые на прошлом опросе: длительность могла появиться.
+	// Удаленные из истории пропадают из списка сами.
+	var reopened []chromeVisit
+	if len(cursor.Open) > 0 {
+		reopened, _ = queryChromeVisits(db, query+`
+			WHERE v.id IN (?`+strings.Repeat(", ?", len(cursor.Open)-1)+`)
+			ORDER BY v.visit_time, v.id`, int64Args(cursor.Open)...)
+	}
 
-	var maxTime time.Time
+	next := browserCursor{VisitID: cursor.VisitID, Time: cursor.Time}
 	count := 0
 
-	for rows.Next() {
-		var url, title string
-		var visitTime int64
+	for _, v := range reopened {
+		if v.open(now) {
+			next.Open = append(next.Open, v.VisitID)
+			continue
+		}
+		bm.emitChrome(browser, profile, v)
+		count++
+	}
 
-		if err := rows.Scan(&url, &title, &visitTime); err != nil {
+	for _, v := range visits {
+		if next.passed(v.Time, v.VisitID) {
 			continue
 		}
+		next.VisitID, next.Time = v.VisitID, v.Time
 
-		visitTimestamp := chromeEpoch.Add(time.Duration(visitTime) * time.Microsecond)
-		
-		if visitTimestamp.After(lastCheck) {
-			if visitTimestamp.After(maxTime) {
-				maxTime = visitTimestamp
-			}
-
-			if bm.isImportantURL(url) {
-				action := fmt.Sprintf("Visited: %s", url)
-				if title != "" && len(title) < 100 {
-					action = fmt.Sprintf("Visited: %s (%s)", url, title)
-				}
-				bm.callback(browser, action)
-				count++
-			}
+		v.Transition = chromiumTransition(v.transition)
+		if v.Transition == "" || !bm.allowURL(v.URL) {
+			continue
+		}
+		// Курсор уходит дальше, а посещение ждет своей длительности
+		if v.open(now) {
+			next.Open = append(next.Open, v.VisitID)
+			continue
 		}
+		bm.emitChrome(browser, profile, v)
+		count++
 	}
 
-	if !maxTime.IsZero() {
-		bm.lastCheck[browser] = maxTime
-	}
+	bm.advance(key, next)
 
 	if count > 0 {
-		log.Printf("Logged %d new %s visits", count, browser)
+		log.Printf("Logged %d new %s visits (profile %s)", count, browser, profile.Name)
 	}
 }
 
-func (bm *BrowserMonitor) readFirefoxHistory(historyPath string) {
-	if _, err := os.Stat(historyPath); os.IsNotExist(err) {
-		return
-	}
-
-	tempPath := historyPath + ".tmp"
-	if err := bm.copyFile(historyPath, tempPath); err != nil {
-		return
-	}
-	defer os.Remove(tempPath)
+func (bm *BrowserMonitor) emitChrome(browser string, profile browserProfile, v chromeVisit) {
+	visit := v.BrowserVisit
+	visit.Browser, visit.Profile = browser, profile.Name
+	visit.DurationSec = time.Duration(v.duration * int64(time.Microsecond)).Seconds()
+	bm.emit(visit, v.term)
+}
 
-	db, err := sql.Open("sqlite3", tempPath)
+func (bm *BrowserMonitor) readFirefoxHistory(historyPath, browser string, profile browserProfile, key string) {
+	db, cleanup, err := bm.openSnapshot(historyPath)
 	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot snapshot %s history: %v", browser, err)
+		}
 		return
 	}
-	defer db.Close()
+	defer cleanup()
 
-	lastCheck := bm.lastCheck["Firefox"]
-	if lastCheck.IsZero() {
-		lastCheck = time.Now().Add(-5 * time.Minute)
-	}
+	cursor := bm.cursor(key)
 
+	// Firefox не хранит длительность посещения
 	query := `
-		SELECT url, title, last_visit_date 
-		FROM moz_places 
-		WHERE last_visit_date > ? 
-		ORDER BY last_visit_date DESC 
-		LIMIT 50
+		SELECT v.id, p.url, COALESCE(p.title, ''), v.visit_date, v.visit_type,
+		       COALESCE(rp.url, '')
+		FROM moz_historyvisits v
+		JOIN moz_places p ON p.id = v.place_id
+		LEFT JOIN moz_historyvisits fv ON fv.id = v.from_visit
+		LEFT JOIN moz_places rp ON rp.id = fv.place_id
+		WHERE v.visit_date >= ?
+		ORDER BY v.visit_date, v.id
+		LIMIT ?
 	`
 
-	lastCheckMicro := lastCheck.UnixMicro()
-
-	rows, err := db.Query(query, lastCheckMicro)
+	rows, err := db.Query(query, cursor.Time.UnixMicro(), maxVisitsPerPoll)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 
-	var maxTime time.Time
+	next := cursor
 	count := 0
 
 	for rows.Next() {
-		var url, title string
-		var visitTime int64
+		var url, title, referrer string
+		var id, visitTime, visitType int64
 
-		if err := rows.Scan(&url, &title, &visitTime); err != nil {
+		if err := rows.Scan(&id, &url, &title, &visitTime, &visitType, &referrer); err != nil {
 			continue
 		}
 
 		visitTimestamp := time.UnixMicro(visitTime)
-		
-		if visitTimestamp.After(lastCheck) {
-			if visitTimestamp.After(maxTime) {
-				maxTime = visitTimestamp
-			}
-
-			if bm.isImportantURL(url) {
-				action := fmt.Sprintf("Visited: %s", url)
-				if title != "" && len(title) < 100 {
-					action = fmt.Sprintf("Visited: %s (%s)", url, title)
-				}
-				bm.callback("Firefox", action)
-				count++
-			}
+		if next.passed(visitTimestamp, id) {
+			continue
 		}
-	}
+		next = browserCursor{VisitID: id, Time: visitTimestamp}
 
-	if !maxTime.IsZero() {
-		bm.lastCheck["Firefox"] = maxTime
+		kind := firefoxTransition(visitType)
+		if kind == "" || !bm.allowURL(url) {
+			continue
+		}
+		bm.emit(models.BrowserVisit{
+			Browser:    browser,
+			Profile:    profile.Name,
+			URL:        url,
+			Title:      title,
+			Time:       visitTimestamp,
+			VisitID:    id,
+			Transition: kind,
+			Referrer:   referrer,
+		}, "")
+		count++
 	}
 
+	bm.advance(key, next)
+
 	if count > 0 {
-		log.Printf("Logged %d new Firefox visits", count)
+		log.Printf("Logged %d new %s visits (profile %s)", count, browser, profile.Name)
 	}
 }
 
-func (bm *BrowserMonitor) isImportantURL(url string) bool {
-	if strings.HasPrefix(url, "chrome://") || 
-	   strings.HasPrefix(url, "edge://") ||
-	   strings.HasPrefix(url, "about:") ||
-	   strings.HasPrefix(url, "chrome-extension://") ||
-	   strings.HasPrefix(url, "moz-extension://") {
-		return false
+// emit распознает поисковый запрос и скрывает секреты в адресах и запросе.
+// term — запрос из базы браузера, если он там сохранен.
+func (bm *BrowserMonitor) emit(v models.BrowserVisit, term string) {
+	// Перезагрузка и редирект страницы результатов — не новый поиск
+	if v.Transition != TransitionReload && v.Transition != TransitionRedirect {
+		v.SearchEngine, v.Query = parseSearch(v.URL)
+		if v.Query == "" && term != "" {
+			v.SearchEngine, v.Query = searchHost(v.URL), normalizeQuery(term)
+		}
 	}
 
-	if strings.Contains(url, "google.com/search") ||
-	   strings.Contains(url, "bing.com/search") ||
-	   strings.Contains(url, "youtube.com/watch") ||
-	   strings.Contains(url, "github.com") ||
-	   strings.Contains(url, "stackoverflow.com") ||
-	   strings.Contains(url, "facebook.com") ||
-	   strings.Contains(url, "twitter.com") ||
-	   strings.Contains(url, "instagram.com") ||
-	   strings.Contains(url, "reddit.com") ||
-	   strings.Contains(url, "wikipedia.org") ||
-	   len(url) < 200 {
-		return true
-	}
+	v.URL = bm.redactText(v.URL)
+	v.Referrer = bm.redactText(v.Referrer)
+	v.Query = bm.redactText(v.Query)
+	bm.callback(v)
+}
 
-	return false
+func (bm *BrowserMonitor) redactText(s string) string {
+	for _, re := range bm.redact {
+		s = re.ReplaceAllStringFunc(s, redactMatch(re))
+	}
+	return s
 }
 
-func (bm *BrowserMonitor) copyFile(src, dst string) error {
-	source, err := os.Open(src)
+// SetURLRules заменяет фильтр адресов. Набор с DryRun не трогает действующий
+// фильтр, а проверяется рядом с ним; применение обычного набора завершает
+// проверку. Отчет начинается заново.
+func (bm *BrowserMonitor) SetURLRules(set models.URLRules) error {
+	filter, err := compileURLRules(set)
 	if err != nil {
 		return err
 	}
-	defer source.Close()
 
-	destination, err := os.Create(dst)
-	if err != nil {
-		return err
+	bm.mu.Lock()
+	if set.DryRun {
+		bm.shadow = filter
+	} else {
+		bm.filter = filter
+		bm.shadow = nil
+	}
+	bm.mu.Unlock()
+
+	log.Printf("URL rules %q applied: %d rules, dry run %v", set.Version, len(set.Rules), set.DryRun)
+	return nil
+}
+
+// URLRulesVersion — версия действующего фильтра
+func (bm *BrowserMonitor) URLRulesVersion() string {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	return bm.filter.version
+}
+
+// URLRulesReport — что совпало с каждым правилом с момента их применения.
+// Во время dry run отчет по проверяемым правилам, иначе по действующим.
+func (bm *BrowserMonitor) URLRulesReport() (version string, dryRun bool, report []models.URLRuleReport) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	f := bm.filter
+	if bm.shadow != nil {
+		f = bm.shadow
 	}
-	defer destination.Close()
+	return f.version, f.dryRun, f.Report()
+}
 
-	_, err = io.Copy(destination, source)
-	return err
+func (bm *BrowserMonitor) allowURL(url string) bool {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	sample := bm.redactText(url)
+	if bm.shadow != nil {
+		bm.shadow.allow(url, sample)
+	}
+	return bm.filter.allow(url, sample)
 }
 
 func (bm *BrowserMonitor) UpdateUsername(username string) {
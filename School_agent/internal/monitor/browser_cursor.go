@@ -0,0 +1,75 @@
+package monitor
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	cursorsFile = "browser_cursors.json"
+	// Глубина чтения истории профиля, который агент видит впервые
+	defaultBackfill = time.Hour
+)
+
+// browserCursor — последнее прочитанное посещение профиля. Посещения с тем же
+// временем различаются по id, поэтому повторно не отправляются.
+type browserCursor struct {
+	VisitID int64     `json:"visit_id"`
+	Time    time.Time `json:"time"`
+	// Посещения до курсора, которые еще не закрыты и не отправлены
+	Open []int64 `json:"open,omitempty"`
+}
+
+// passed — посещение уже было прочитано
+func (c browserCursor) passed(t time.Time, id int64) bool {
+	return t.Before(c.Time) || (t.Equal(c.Time) && id <= c.VisitID)
+}
+
+// cursorKey — свой курсор у каждого профиля каждого браузера каждого пользователя
+func cursorKey(user, browser string, profile browserProfile) string {
+	return strings.ToLower(user) + "|" + browser + "|" + strings.ToLower(profile.Dir)
+}
+
+// cursor возвращает сохраненный курсор; для нового профиля история
+// читается на глубину backfill
+func (bm *BrowserMonitor) cursor(key string) browserCursor {
+	if c, ok := bm.cursors[key]; ok {
+		return c
+	}
+	return browserCursor{Time: time.Now().Add(-bm.backfill)}
+}
+
+func (bm *BrowserMonitor) advance(key string, c browserCursor) {
+	if old, ok := bm.cursors[key]; ok && old.VisitID == c.VisitID && old.Time.Equal(c.Time) && slices.Equal(old.Open, c.Open) {
+		return
+	}
+	bm.cursors[key] = c
+	bm.cursorsDirty = true
+}
+
+func (bm *BrowserMonitor) loadCursors() {
+	data, err := os.ReadFile(bm.cursorsPath)
+	if err != nil {
+		return
+	}
+	var cursors map[string]browserCursor
+	if json.Unmarshal(data, &cursors) == nil && cursors != nil {
+		bm.cursors = cursors
+	}
+}
+
+func (bm *BrowserMonitor) saveCursors() {
+	if !bm.cursorsDirty {
+		return
+	}
+	data, err := json.Marshal(bm.cursors)
+	if err != nil {
+		return
+	}
+	if os.WriteFile(bm.cursorsPath, data, 0644) == nil {
+		bm.cursorsDirty = false
+	}
+}
@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCursorPassed(t *testing.T) {
+	at := time.Date(2024, 9, 2, 10, 0, 0, 0, time.UTC)
+	c := browserCursor{VisitID: 10, Time: at}
+	tests := []struct {
+		desc string
+		t    time.Time
+		id   int64
+		want bool
+	}{
+		{"earlier", at.Add(-time.Microsecond), 50, true},
+		{"same time, lower id", at, 9, true},
+		{"the cursor visit", at, 10, true},
+		{"same time, higher id", at, 11, false},
+		{"later", at.Add(time.Microsecond), 1, false},
+	}
+	for _, tt := range tests {
+		if got := c.passed(tt.t, tt.id); got != tt.want {
+			t.Errorf("%s: passed = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func visitIDs(rec *visitRecorder) []int64 {
+	var ids []int64
+	for _, v := range rec.take() {
+		ids = append(ids, v.VisitID)
+	}
+	return ids
+}
+
+// Посещения с одинаковым временем отправляются по одному разу
+func TestChromeEqualTimestampsEmittedOnce(t *testing.T) {
+	dir := t.TempDir()
+	h := newChromeHistory(t, filepath.Join(dir, "Default"))
+	profile := browserProfile{Name: "Default", Dir: filepath.Join(dir, "Default")}
+	key := cursorKey("pupil", "chrome", profile)
+
+	at := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	h.visit(1, "https://a.example/", at, 0, time.Second)
+	h.visit(2, "https://b.example/", at, 0, time.Second)
+
+	rec := &visitRecorder{}
+	bm := newTestBrowserMonitor(t, filepath.Join(dir, "state"), rec)
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	if ids := visitIDs(rec); len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("first pass: %v, want [1 2]", ids)
+	}
+
+	// Новое посещение с тем же временем: отправляется только оно
+	h.visit(3, "https://c.example/", at, 0, time.Second)
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	if ids := visitIDs(rec); len(ids) != 1 || ids[0] != 3 {
+		t.Fatalf("second pass: %v, want [3]", ids)
+	}
+
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	if ids := visitIDs(rec); len(ids) != 0 {
+		t.Fatalf("visits emitted twice: %v", ids)
+	}
+}
+
+// Курсор сохраняется в каталоге состояния: после перезапуска агента
+// прочитанные посещения не отправляются повторно
+func TestChromeCursorSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	stateDir := filepath.Join(dir, "state")
+	h := newChromeHistory(t, filepath.Join(dir, "Default"))
+	profile := browserProfile{Name: "Default", Dir: filepath.Join(dir, "Default")}
+	key := cursorKey("pupil", "chrome", profile)
+
+	now := time.Now()
+	h.visit(1, "https://a.example/", now.Add(-3*time.Minute), 0, time.Second)
+	h.visit(2, "https://b.example/", now.Add(-2*time.Minute), 0, time.Second)
+
+	rec := &visitRecorder{}
+	bm := newTestBrowserMonitor(t, stateDir, rec)
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	bm.saveCursors()
+	if ids := visitIDs(rec); len(ids) != 2 {
+		t.Fatalf("before restart: %v, want [1 2]", ids)
+	}
+
+	h.visit(3, "https://c.example/", now.Add(-time.Minute), 0, time.Second)
+	restarted := newTestBrowserMonitor(t, stateDir, rec)
+	restarted.readChromeHistory(h.path, "chrome", profile, key)
+	if ids := visitIDs(rec); len(ids) != 1 || ids[0] != 3 {
+		t.Fatalf("after restart: %v, want [3]", ids)
+	}
+}
@@ -0,0 +1,118 @@
+package monitor
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// browserProfile — профиль браузера: отображаемое имя и каталог с историей
+type browserProfile struct {
+	Name string
+	Dir  string
+}
+
+// chromiumProfiles читает список профилей из Local State (profile.info_cache).
+// Если файла нет, проверяется только Default. Opera хранит историю прямо в
+// корне — тогда корень считается профилем Default.
+func chromiumProfiles(userData string) []browserProfile {
+	if _, err := os.Stat(filepath.Join(userData, "History")); err == nil {
+		return []browserProfile{{Name: "Default", Dir: userData}}
+	}
+
+	var state struct {
+		Profile struct {
+			InfoCache map[string]struct {
+				Name string `json:"name"`
+			} `json:"info_cache"`
+		} `json:"profile"`
+	}
+
+	data, err := os.ReadFile(filepath.Join(userData, "Local State"))
+	if err != nil || json.Unmarshal(data, &state) != nil || len(state.Profile.InfoCache) == 0 {
+		return []browserProfile{{Name: "Default", Dir: filepath.Join(userData, "Default")}}
+	}
+
+	var profiles []browserProfile
+	for dir, info := range state.Profile.InfoCache {
+		name := info.Name
+		if name == "" {
+			name = dir
+		}
+		profiles = append(profiles, browserProfile{Name: name, Dir: filepath.Join(userData, dir)})
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Dir < profiles[j].Dir })
+	return profiles
+}
+
+// firefoxProfiles читает секции [ProfileN] из profiles.ini. Если файла нет,
+// профилями считаются все каталоги в Profiles.
+func firefoxProfiles(root string) []browserProfile {
+	f, err := os.Open(filepath.Join(root, "profiles.ini"))
+	if err != nil {
+		return scanProfileDirs(filepath.Join(root, "Profiles"))
+	}
+	defer f.Close()
+
+	var profiles []browserProfile
+	var section string
+	var name, path string
+	relative := true
+	flush := func() {
+		if strings.HasPrefix(section, "Profile") && path != "" {
+			dir := filepath.FromSlash(path)
+			if relative {
+				dir = filepath.Join(root, dir)
+			}
+			if name == "" {
+				name = filepath.Base(dir)
+			}
+			profiles = append(profiles, browserProfile{Name: name, Dir: dir})
+		}
+		name, path, relative = "", "", true
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			flush()
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "Name":
+			name = strings.TrimSpace(value)
+		case "Path":
+			path = strings.TrimSpace(value)
+		case "IsRelative":
+			relative = strings.TrimSpace(value) != "0"
+		}
+	}
+	flush()
+	return profiles
+}
+
+func scanProfileDirs(dir string) []browserProfile {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var profiles []browserProfile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			profiles = append(profiles, browserProfile{Name: entry.Name(), Dir: filepath.Join(dir, entry.Name())})
+		}
+	}
+	return profiles
+}
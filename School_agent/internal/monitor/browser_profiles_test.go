@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, body string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChromiumProfiles(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "Local State"), `{"profile": {"info_cache": {
+		"Profile 2": {"name": ""},
+		"Default": {"name": "Личный"},
+		"Profile 1": {"name": "Школа"}
+	}}}`)
+
+	want := []browserProfile{
+		{Name: "Личный", Dir: filepath.Join(root, "Default")},
+		{Name: "Школа", Dir: filepath.Join(root, "Profile 1")},
+		{Name: "Profile 2", Dir: filepath.Join(root, "Profile 2")},
+	}
+	if got := chromiumProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("profiles from Local State:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestChromiumProfilesWithoutLocalState(t *testing.T) {
+	root := t.TempDir()
+	want := []browserProfile{{Name: "Default", Dir: filepath.Join(root, "Default")}}
+	if got := chromiumProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("no Local State: got %+v, want %+v", got, want)
+	}
+
+	writeFile(t, filepath.Join(root, "Local State"), `{"profile": {}}`)
+	if got := chromiumProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("empty info_cache: got %+v, want %+v", got, want)
+	}
+}
+
+// Opera хранит History прямо в корне
+func TestChromiumProfilesHistoryInRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "History"), "")
+	writeFile(t, filepath.Join(root, "Local State"), `{"profile": {"info_cache": {"Default": {"name": "x"}}}}`)
+
+	want := []browserProfile{{Name: "Default", Dir: root}}
+	if got := chromiumProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestFirefoxProfiles(t *testing.T) {
+	root := t.TempDir()
+	external := filepath.Join(t.TempDir(), "school")
+	writeFile(t, filepath.Join(root, "profiles.ini"), `; comment
+[Install308046B0AF4A39CB]
+Default=Profiles/abc.default-release
+Locked=1
+
+[Profile1]
+Name=default
+IsRelative=1
+Path=Profiles/xyz.default
+
+[Profile0]
+Name=default-release
+IsRelative=1
+Path=Profiles/abc.default-release
+Default=1
+
+[Profile2]
+IsRelative=0
+Path=`+external+`
+
+[General]
+StartWithLastProfile=1
+Version=2
+`)
+
+	want := []browserProfile{
+		{Name: "default", Dir: filepath.Join(root, "Profiles", "xyz.default")},
+		{Name: "default-release", Dir: filepath.Join(root, "Profiles", "abc.default-release")},
+		{Name: "school", Dir: external},
+	}
+	if got := firefoxProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("profiles from profiles.ini:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestFirefoxProfilesWithoutIni(t *testing.T) {
+	root := t.TempDir()
+	if got := firefoxProfiles(root); len(got) != 0 {
+		t.Errorf("no Profiles dir: got %+v", got)
+	}
+
+	for _, dir := range []string{"abc.default-release", "xyz.default"} {
+		if err := os.MkdirAll(filepath.Join(root, "Profiles", dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(root, "Profiles", "times.json"), "{}")
+
+	want := []browserProfile{
+		{Name: "abc.default-release", Dir: filepath.Join(root, "Profiles", "abc.default-release")},
+		{Name: "xyz.default", Dir: filepath.Join(root, "Profiles", "xyz.default")},
+	}
+	if got := firefoxProfiles(root); !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned Profiles:\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+)
+
+// searchEngine — поисковик: домен второго уровня (любая зона), путь страницы
+// результатов и параметр с запросом
+type searchEngine struct {
+	name   string
+	domain string
+	path   string
+	param  string
+}
+
+var searchEngines = []searchEngine{
+	{"Google", "google", "/search", "q"},
+	{"Bing", "bing", "/search", "q"},
+	{"Yandex", "yandex", "/search", "text"},
+	{"Yandex", "ya", "/search", "text"},
+	{"DuckDuckGo", "duckduckgo", "/", "q"},
+	{"YouTube", "youtube", "/results", "search_query"},
+	{"Wikipedia", "wikipedia", "/", "search"},
+}
+
+// parseSearch достает поисковый запрос из адреса страницы результатов
+func parseSearch(rawURL string) (engine, query string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", ""
+	}
+	labels := strings.Split(strings.ToLower(u.Hostname()), ".")
+
+	for _, e := range searchEngines {
+		if !hasDomainLabel(labels, e.domain) || !strings.HasPrefix(u.Path, e.path) {
+			continue
+		}
+		if q := normalizeQuery(u.Query().Get(e.param)); q != "" {
+			return e.name, q
+		}
+	}
+	return "", ""
+}
+
+// hasDomainLabel: google.com, www.google.co.uk, ru.wikipedia.org — но не
+// google (без зоны) и не notgoogle.com
+func hasDomainLabel(labels []string, name string) bool {
+	for i := 0; i < len(labels)-1; i++ {
+		if labels[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeQuery(q string) string {
+	return strings.ToLower(strings.Join(strings.Fields(q), " "))
+}
+
+// searchHost — имя поисковика для запросов из keyword_search_terms, когда
+// адрес не распознан (свои поисковые системы пользователя)
+func searchHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// chromiumHasSearchTerms — в старых и урезанных базах таблицы может не быть
+func chromiumHasSearchTerms(db *sql.DB) bool {
+	var name string
+	err := db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = 'keyword_search_terms'`).Scan(&name)
+	return err == nil
+}
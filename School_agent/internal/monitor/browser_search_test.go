@@ -0,0 +1,36 @@
+package monitor
+
+import "testing"
+
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		url, engine, query string
+	}{
+		{"https://www.google.com/search?q=photosynthesis&oq=photo", "Google", "photosynthesis"},
+		{"https://www.google.co.uk/search?q=Pythagorean+theorem", "Google", "pythagorean theorem"},
+		{"https://www.bing.com/search?q=%D0%BA%D0%B2%D0%B0%D0%B4%D1%80%D0%B0%D1%82&form=QBLH", "Bing", "квадрат"},
+		{"https://yandex.ru/search/?text=%D0%BA%D0%BE%D1%80%D0%BE%D0%BD%D0%B0%D0%B2%D0%B8%D1%80%D1%83%D1%81&lr=213", "Yandex", "коронавирус"},
+		{"https://ya.ru/search/?text=%20%20Pushkin%20%20poems%20", "Yandex", "pushkin poems"},
+		{"https://duckduckgo.com/?q=go+generics&ia=web", "DuckDuckGo", "go generics"},
+		{"https://www.youtube.com/results?search_query=minecraft%20build", "YouTube", "minecraft build"},
+		{"https://ru.wikipedia.org/w/index.php?search=%D0%9C%D0%BE%D1%81%D0%BA%D0%B2%D0%B0", "Wikipedia", "москва"},
+		{"https://www.google.com/search?q=c%2B%2B+%26+rust", "Google", "c++ & rust"},
+
+		// Не страницы результатов
+		{"https://www.google.com/maps?q=school", "", ""},
+		{"https://www.google.com/search?tbm=isch", "", ""},
+		{"https://www.google.com/search?q=+++", "", ""},
+		{"https://notgoogle.com/search?q=x", "", ""},
+		{"https://mail.yandex.ru/?text=x", "", ""},
+		{"https://school.example/search?q=x", "", ""},
+		{"http://google/search?q=x", "", ""},
+		{"not a url", "", ""},
+		{"file:///C:/search?q=x", "", ""},
+	}
+	for _, tt := range tests {
+		engine, query := parseSearch(tt.url)
+		if engine != tt.engine || query != tt.query {
+			t.Errorf("parseSearch(%q) = %q, %q; want %q, %q", tt.url, engine, query, tt.engine, tt.query)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package monitor
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	snapshotDirName = "browser_snapshots"
+	// Сколько раз пытаться снять копию, пока браузер пишет в базу
+	snapshotAttempts = 3
+)
+
+// Файлы рядом с базой SQLite: журнал WAL с его индексом -shm и журнал
+// отката. Без -wal копия не видит последних посещений — браузер еще не
+// перенес их в базу.
+var sqliteSidecars = []string{"-wal", "-shm", "-journal"}
+
+// cleanSnapshots удаляет копии, оставшиеся после аварийного завершения агента
+func cleanSnapshots(root string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		os.RemoveAll(filepath.Join(root, entry.Name()))
+	}
+	if len(entries) > 0 {
+		log.Printf("Removed %d stale browser snapshot(s)", len(entries))
+	}
+}
+
+// openSnapshot копирует базу истории вместе с журналом во временный
+// каталог агента и открывает копию. cleanup закрывает базу и удаляет копию.
+func (bm *BrowserMonitor) openSnapshot(dbPath string) (db *sql.DB, cleanup func(), err error) {
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, nil, err
+	}
+	// Прежние версии агента клали копию в профиль пользователя
+	os.Remove(dbPath + ".tmp")
+
+	if err := os.MkdirAll(bm.snapshotRoot, 0700); err != nil {
+		return nil, nil, err
+	}
+	dir, err := os.MkdirTemp(bm.snapshotRoot, "snap-")
+	if err != nil {
+		return nil, nil, err
+	}
+	remove := func() { os.RemoveAll(dir) }
+
+	copyPath := filepath.Join(dir, filepath.Base(dbPath))
+	if err := snapshotFiles(dbPath, copyPath); err != nil {
+		remove()
+		return nil, nil, err
+	}
+
+	db, err = sql.Open("sqlite3", copyPath)
+	if err != nil {
+		remove()
+		return nil, nil, err
+	}
+	return db, func() { db.Close(); remove() }, nil
+}
+
+// snapshotFiles копирует базу и журналы. Если браузер успел их изменить
+// за время копирования, копия может быть несогласованной — тогда повтор.
+func snapshotFiles(src, dst string) error {
+	var err error
+	for attempt := 0; attempt < snapshotAttempts; attempt++ {
+		before := fileStamps(src)
+		if err = copyFile(src, dst); err != nil {
+			return err
+		}
+		for _, suffix := range sqliteSidecars {
+			os.Remove(dst + suffix)
+			if _, statErr := os.Stat(src + suffix); statErr != nil {
+				continue
+			}
+			if err = copyFile(src+suffix, dst+suffix); err != nil {
+				return err
+			}
+		}
+		if fileStamps(src) == before {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return fmt.Errorf("%s keeps changing, snapshot may be inconsistent", src)
+}
+
+// fileStamps — размеры и время изменения базы и журналов
+func fileStamps(src string) string {
+	var stamp string
+	for _, suffix := range append([]string{""}, sqliteSidecars...) {
+		if info, err := os.Stat(src + suffix); err == nil {
+			stamp += fmt.Sprintf("%d/%d;", info.Size(), info.ModTime().UnixNano())
+		} else {
+			stamp += "-;"
+		}
+	}
+	return stamp
+}
+
+func copyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	destination, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	}
+	return destination.Close()
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshotFilesCopiesSidecars(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "History")
+	for suffix, body := range map[string]string{"": "db", "-wal": "wal", "-shm": "shm"} {
+		writeFile(t, src+suffix, body)
+	}
+	dst := filepath.Join(dir, "copy", "History")
+	// Журнал отката от прошлой копии: у исходной базы его нет
+	writeFile(t, dst+"-journal", "stale")
+
+	if err := snapshotFiles(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for suffix, want := range map[string]string{"": "db", "-wal": "wal", "-shm": "shm"} {
+		if got, err := os.ReadFile(dst + suffix); err != nil || string(got) != want {
+			t.Errorf("copy of History%s = %q, %v; want %q", suffix, got, err, want)
+		}
+	}
+	if _, err := os.Stat(dst + "-journal"); !os.IsNotExist(err) {
+		t.Errorf("stale -journal kept: %v", err)
+	}
+}
+
+// Посещения, которые браузер еще держит в WAL, видны в копии
+func TestSnapshotSeesUncheckpointedWAL(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "History")
+	db, err := sql.Open("sqlite3", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, q := range []string{
+		`PRAGMA journal_mode=WAL`,
+		`PRAGMA wal_autocheckpoint=0`,
+		`CREATE TABLE urls (id INTEGER PRIMARY KEY, url TEXT)`,
+		`INSERT INTO urls (url) VALUES ('https://school.example/')`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	if _, err := os.Stat(src + "-wal"); err != nil {
+		t.Fatalf("no WAL next to the database: %v", err)
+	}
+
+	bm := &BrowserMonitor{snapshotRoot: filepath.Join(dir, "snapshots")}
+	copyDB, cleanup, err := bm.openSnapshot(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err = copyDB.QueryRow(`SELECT COUNT(*) FROM urls`).Scan(&n)
+	cleanup()
+	if err != nil || n != 1 {
+		t.Fatalf("rows in snapshot: %d, %v; want 1", n, err)
+	}
+
+	if entries, _ := os.ReadDir(bm.snapshotRoot); len(entries) != 0 {
+		t.Errorf("snapshot left after cleanup: %v", entries)
+	}
+}
+
+func TestCleanSnapshotsRemovesStaleCopies(t *testing.T) {
+	root := filepath.Join(t.TempDir(), snapshotDirName)
+	writeFile(t, filepath.Join(root, "snap-1", "History"), "db")
+	writeFile(t, filepath.Join(root, "snap-1", "History-wal"), "wal")
+	writeFile(t, filepath.Join(root, "snap-2", "places.sqlite"), "db")
+	writeFile(t, filepath.Join(root, "stray.tmp"), "")
+
+	cleanSnapshots(root)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("snapshot root removed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("left after cleanup: %v", entries)
+	}
+
+	// Каталога еще нет — ничего не делать
+	cleanSnapshots(filepath.Join(t.TempDir(), "missing"))
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"testing"
+	"time"
+)
+
+// chromeHistory — база History с таблицами, которые читает агент
+type chromeHistory struct {
+	t    *testing.T
+	path string
+	db   *sql.DB
+}
+
+func newChromeHistory(t *testing.T, dir string) *chromeHistory {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "History")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	h := &chromeHistory{t: t, path: path, db: db}
+	h.exec(`CREATE TABLE urls (id INTEGER PRIMARY KEY, url TEXT, title TEXT)`)
+	h.exec(`CREATE TABLE visits (id INTEGER PRIMARY KEY, url INTEGER, visit_time INTEGER,
+		from_visit INTEGER, transition INTEGER, visit_duration INTEGER)`)
+	return h
+}
+
+func (h *chromeHistory) exec(query string, args ...interface{}) {
+	h.t.Helper()
+	if _, err := h.db.Exec(query, args...); err != nil {
+		h.t.Fatalf("%s: %v", query, err)
+	}
+}
+
+// visit добавляет посещение адреса; duration 0 — вкладка еще открыта
+func (h *chromeHistory) visit(id int64, url string, at time.Time, transition int64, duration time.Duration) {
+	h.exec(`INSERT OR IGNORE INTO urls (id, url, title) VALUES (?, ?, '')`, id, url)
+	h.exec(`INSERT INTO visits (id, url, visit_time, from_visit, transition, visit_duration) VALUES (?, ?, ?, 0, ?, ?)`,
+		id, id, toChromeTime(at), transition, duration.Microseconds())
+}
+
+type visitRecorder struct {
+	visits []models.BrowserVisit
+}
+
+func (r *visitRecorder) take() []models.BrowserVisit {
+	v := r.visits
+	r.visits = nil
+	return v
+}
+
+func newTestBrowserMonitor(t *testing.T, stateDir string, rec *visitRecorder) *BrowserMonitor {
+	return NewBrowserMonitor(config.BrowserConfig{BackfillMin: 180}, stateDir, "pupil", func(v models.BrowserVisit) {
+		rec.visits = append(rec.visits, v)
+	})
+}
+
+func TestChromeVisitWaitsForDuration(t *testing.T) {
+	dir := t.TempDir()
+	h := newChromeHistory(t, filepath.Join(dir, "Default"))
+	profile := browserProfile{Name: "Default", Dir: filepath.Join(dir, "Default")}
+	key := cursorKey("pupil", "chrome", profile)
+
+	now := time.Now()
+	h.visit(1, "https://stale.example/", now.Add(-2*time.Hour), 1, 0)
+	h.visit(2, "https://open.example/", now.Add(-10*time.Minute), 1, 0)
+	h.visit(3, "https://closed.example/", now.Add(-5*time.Minute), 0, 30*time.Second)
+	h.visit(4, "https://redirect.example/", now.Add(-4*time.Minute), chromeServerRedirect, 0)
+
+	rec := &visitRecorder{}
+	bm := newTestBrowserMonitor(t, filepath.Join(dir, "state"), rec)
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+
+	got := rec.take()
+	want := []struct {
+		id       int64
+		duration float64
+	}{{1, 0}, {3, 30}, {4, 0}}
+	if len(got) != len(want) {
+		t.Fatalf("first pass: got %+v, want visits 1, 3, 4", got)
+	}
+	for i, w := range want {
+		if got[i].VisitID != w.id || got[i].DurationSec != w.duration {
+			t.Fatalf("visit %d: got id %d duration %v, want %d %v", i, got[i].VisitID, got[i].DurationSec, w.id, w.duration)
+		}
+	}
+
+	// Открытое посещение не отправлено, пока вкладка не ушла со страницы
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	if got := rec.take(); len(got) != 0 {
+		t.Fatalf("open visit emitted: %+v", got)
+	}
+
+	h.exec(`UPDATE visits SET visit_duration = ? WHERE id = 2`, (95 * time.Second).Microseconds())
+	h.visit(5, "https://next.example/", now.Add(-time.Minute), 0, time.Second)
+	bm.readChromeHistory(h.path, "chrome", profile, key)
+	got = rec.take()
+	if len(got) != 2 || got[0].VisitID != 2 || got[0].DurationSec != 95 || got[1].VisitID != 5 {
+		t.Fatalf("after the visit closed: got %+v, want 2 (95s) and 5", got)
+	}
+	if open := bm.cursors[key].Open; len(open) != 0 {
+		t.Fatalf("closed visit still pending: %v", open)
+	}
+}
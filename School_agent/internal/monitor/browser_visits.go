@@ -0,0 +1,134 @@
+package monitor
+
+import (
+	"database/sql"
+	"school_agent/internal/models"
+	"time"
+)
+
+// Не больше стольких посещений за один опрос профиля; остальные
+// дочитываются на следующем опросе
+const maxVisitsPerPoll = 500
+
+// Chromium хранит время в микросекундах от 1601-01-01 UTC. Разница с Unix
+// эпохой не помещается в time.Duration, поэтому пересчет идет через Unix-время.
+const chromeEpochOffsetMicro = 11644473600 * 1000000
+
+func toChromeTime(t time.Time) int64 {
+	return t.UnixMicro() + chromeEpochOffsetMicro
+}
+
+func fromChromeTime(v int64) time.Time {
+	return time.UnixMicro(v - chromeEpochOffsetMicro)
+}
+
+// Тип перехода, общий для всех браузеров
+const (
+	TransitionLink     = "link"
+	TransitionTyped    = "typed"
+	TransitionBookmark = "bookmark"
+	TransitionReload   = "reload"
+	TransitionRedirect = "redirect"
+	TransitionForm     = "form"
+	TransitionKeyword  = "keyword"
+	TransitionOther    = "other"
+)
+
+// Биты ui::PageTransition в Chromium
+const (
+	chromeCoreMask       = 0xFF
+	chromeClientRedirect = 0x40000000
+	chromeServerRedirect = 0x80000000
+)
+
+// chromiumTransition переводит visits.transition в общий тип.
+// Пустая строка — служебная загрузка фрейма, такие посещения не пишутся.
+func chromiumTransition(t int64) string {
+	if t&(chromeClientRedirect|chromeServerRedirect) != 0 {
+		return TransitionRedirect
+	}
+	switch t & chromeCoreMask {
+	case 0, 4: // LINK, MANUAL_SUBFRAME
+		return TransitionLink
+	case 1, 5: // TYPED, GENERATED (набран в адресной строке)
+		return TransitionTyped
+	case 2: // AUTO_BOOKMARK
+		return TransitionBookmark
+	case 3: // AUTO_SUBFRAME
+		return ""
+	case 7: // FORM_SUBMIT
+		return TransitionForm
+	case 8: // RELOAD
+		return TransitionReload
+	case 9, 10: // KEYWORD, KEYWORD_GENERATED
+		return TransitionKeyword
+	}
+	return TransitionOther
+}
+
+// firefoxTransition переводит moz_historyvisits.visit_type в общий тип
+func firefoxTransition(t int64) string {
+	switch t {
+	case 1, 8: // LINK, FRAMED_LINK
+		return TransitionLink
+	case 2: // TYPED
+		return TransitionTyped
+	case 3: // BOOKMARK
+		return TransitionBookmark
+	case 4: // EMBED
+		return ""
+	case 5, 6: // REDIRECT_PERMANENT, REDIRECT_TEMPORARY
+		return TransitionRedirect
+	case 9: // RELOAD
+		return TransitionReload
+	}
+	return TransitionOther
+}
+
+// Сколько посещение Chromium ждет своей длительности: браузер мог упасть,
+// а вкладка — остаться открытой на весь день
+const openVisitTimeout = time.Hour
+
+// chromeVisit — строка visits вместе с тем, что нужно до отправки
+type chromeVisit struct {
+	models.BrowserVisit
+	transition int64
+	duration   int64 // мкс
+	term       string
+}
+
+// open: Chromium пишет visit_duration, только когда посещение закончилось
+// (переход на другую страницу или закрытие вкладки). У редиректов
+// длительности не бывает.
+func (v chromeVisit) open(now time.Time) bool {
+	return v.duration == 0 && v.Transition != TransitionRedirect && now.Sub(v.Time) < openVisitTimeout
+}
+
+func queryChromeVisits(db *sql.DB, query string, args ...interface{}) ([]chromeVisit, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var visits []chromeVisit
+	for rows.Next() {
+		var v chromeVisit
+		var visitTime int64
+		if err := rows.Scan(&v.VisitID, &v.URL, &v.Title, &visitTime, &v.transition, &v.duration, &v.Referrer, &v.term); err != nil {
+			continue
+		}
+		v.Time = fromChromeTime(visitTime)
+		v.Transition = chromiumTransition(v.transition)
+		visits = append(visits, v)
+	}
+	return visits, rows.Err()
+}
+
+func int64Args(ids []int64) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
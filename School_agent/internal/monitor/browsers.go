@@ -0,0 +1,55 @@
+package monitor
+
+import (
+	"log"
+	"school_agent/internal/config"
+	"strings"
+)
+
+// defaultBrowsers — браузеры, которые читаются без настройки.
+// Пути указаны относительно домашнего каталога пользователя.
+var defaultBrowsers = []config.BrowserDef{
+	{Name: "Chrome", Root: "AppData/Local/Google/Chrome/User Data", Format: config.BrowserChromium},
+	{Name: "Edge", Root: "AppData/Local/Microsoft/Edge/User Data", Format: config.BrowserChromium},
+	{Name: "Yandex", Root: "AppData/Local/Yandex/YandexBrowser/User Data", Format: config.BrowserChromium},
+	{Name: "Opera", Root: "AppData/Roaming/Opera Software/Opera Stable", Format: config.BrowserChromium},
+	{Name: "Opera GX", Root: "AppData/Roaming/Opera Software/Opera GX Stable", Format: config.BrowserChromium},
+	{Name: "Brave", Root: "AppData/Local/BraveSoftware/Brave-Browser/User Data", Format: config.BrowserChromium},
+	{Name: "Vivaldi", Root: "AppData/Local/Vivaldi/User Data", Format: config.BrowserChromium},
+	{Name: "Chromium", Root: "AppData/Local/Chromium/User Data", Format: config.BrowserChromium},
+	{Name: "Firefox", Root: "AppData/Roaming/Mozilla/Firefox", Format: config.BrowserFirefox},
+	{Name: "Waterfox", Root: "AppData/Roaming/Waterfox", Format: config.BrowserFirefox},
+	{Name: "LibreWolf", Root: "AppData/Roaming/librewolf", Format: config.BrowserFirefox},
+}
+
+// browserTable объединяет встроенную таблицу с браузерами из конфига
+func browserTable(extra []config.BrowserDef) []config.BrowserDef {
+	table := append([]config.BrowserDef(nil), defaultBrowsers...)
+	for _, def := range extra {
+		if def.Name == "" {
+			continue
+		}
+		if !def.Disabled && def.Format != config.BrowserChromium && def.Format != config.BrowserFirefox {
+			log.Printf("Browser %s: unknown history format %q, skipped", def.Name, def.Format)
+			continue
+		}
+		replaced := false
+		for i := range table {
+			if strings.EqualFold(table[i].Name, def.Name) {
+				table[i], replaced = def, true
+				break
+			}
+		}
+		if !replaced {
+			table = append(table, def)
+		}
+	}
+
+	out := table[:0]
+	for _, def := range table {
+		if !def.Disabled {
+			out = append(out, def)
+		}
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"path/filepath"
+	"school_agent/internal/config"
+	"testing"
+	"time"
+)
+
+func findBrowser(table []config.BrowserDef, name string) (config.BrowserDef, bool) {
+	for _, def := range table {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return config.BrowserDef{}, false
+}
+
+func TestBrowserTableDefaults(t *testing.T) {
+	table := browserTable(nil)
+	if len(table) != len(defaultBrowsers) {
+		t.Fatalf("got %d browsers, want %d built-in", len(table), len(defaultBrowsers))
+	}
+	tests := []struct {
+		name, root, format string
+	}{
+		{"Chrome", "AppData/Local/Google/Chrome/User Data", config.BrowserChromium},
+		{"Yandex", "AppData/Local/Yandex/YandexBrowser/User Data", config.BrowserChromium},
+		{"Opera", "AppData/Roaming/Opera Software/Opera Stable", config.BrowserChromium},
+		{"Firefox", "AppData/Roaming/Mozilla/Firefox", config.BrowserFirefox},
+	}
+	for _, tt := range tests {
+		def, ok := findBrowser(table, tt.name)
+		if !ok || def.Root != tt.root || def.Format != tt.format {
+			t.Errorf("%s: got %+v", tt.name, def)
+		}
+	}
+}
+
+// Запись конфига с тем же именем заменяет встроенную, новые добавляются,
+// Disabled убирает браузер, неизвестный формат пропускается
+func TestBrowserTableOverrides(t *testing.T) {
+	table := browserTable([]config.BrowserDef{
+		{Name: "chrome", Root: "PortableApps/Chrome/Data", Format: config.BrowserChromium},
+		{Name: "Edge", Disabled: true},
+		{Name: "Atom", Root: "AppData/Local/Mail.Ru/Atom/User Data", Format: config.BrowserChromium},
+		{Name: "Safari", Root: "Library/Safari", Format: "webkit"},
+		{Root: "AppData/Local/Nameless"},
+	})
+
+	if len(table) != len(defaultBrowsers) {
+		t.Fatalf("got %d browsers, want %d (one replaced, one disabled, one added)", len(table), len(defaultBrowsers))
+	}
+	if def, ok := findBrowser(table, "chrome"); !ok || def.Root != "PortableApps/Chrome/Data" {
+		t.Errorf("overridden Chrome: %+v", def)
+	}
+	if _, ok := findBrowser(table, "Chrome"); ok {
+		t.Error("built-in Chrome kept next to its override")
+	}
+	if _, ok := findBrowser(table, "Edge"); ok {
+		t.Error("disabled Edge kept")
+	}
+	if def, ok := findBrowser(table, "Atom"); !ok || def.Format != config.BrowserChromium {
+		t.Errorf("added Atom: %+v", def)
+	}
+	if _, ok := findBrowser(table, "Safari"); ok {
+		t.Error("browser with unknown format kept")
+	}
+}
+
+// Каталог из таблицы читается в формате своего браузера, а посещения
+// подписываются именем браузера из таблицы
+func TestCheckBrowserUsesTableEntry(t *testing.T) {
+	home := t.TempDir()
+	def := config.BrowserDef{Name: "Atom", Root: "AppData/Local/Mail.Ru/Atom/User Data", Format: config.BrowserChromium}
+	root := filepath.Join(home, filepath.FromSlash(def.Root))
+	h := newChromeHistory(t, filepath.Join(root, "Default"))
+	h.visit(1, "https://school.example/", time.Now().Add(-time.Minute), 0, time.Second)
+
+	rec := &visitRecorder{}
+	bm := newTestBrowserMonitor(t, t.TempDir(), rec)
+	bm.checkBrowser("pupil", def, root)
+
+	got := rec.take()
+	if len(got) != 1 || got[0].Browser != "Atom" || got[0].Profile != "Default" || got[0].URL != "https://school.example/" {
+		t.Fatalf("visits: %+v", got)
+	}
+
+	// Firefox-формат в том же каталоге не находит places.sqlite
+	def.Format = config.BrowserFirefox
+	bm.checkBrowser("pupil", def, root)
+	if got := rec.take(); len(got) != 0 {
+		t.Fatalf("read Chromium history as Firefox: %+v", got)
+	}
+}
@@ -0,0 +1,25 @@
+package monitor
+
+import "time"
+
+// ProcessEvent — запуск или завершение процесса, полученное от ОС в момент события
+type ProcessEvent struct {
+	Exit bool
+	Info ProcessInfo // для Exit заполнен только PID
+	Time time.Time
+}
+
+// ProcessWatcher — событийный источник запусков и завершений процессов.
+// Закрытие канала означает, что источник отключился и нужен опрос.
+type ProcessWatcher interface {
+	Watch() (<-chan ProcessEvent, error)
+}
+
+// newProcessWatcher: источник, умеющий сам присылать события (FakeSource),
+// используется напрямую, иначе — механизм ОС
+func newProcessWatcher(src ProcessSource) ProcessWatcher {
+	if w, ok := src.(ProcessWatcher); ok {
+		return w
+	}
+	return nativeProcessWatcher(src)
+}
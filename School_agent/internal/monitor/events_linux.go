@@ -0,0 +1,154 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Константы proc connector из linux/connector.h и linux/cn_proc.h
+const (
+	cnIdxProc         = 1
+	cnValProc         = 1
+	procCnMcastListen = 1
+
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	cnMsgLen = 20 // idx, val, seq, ack, len, flags
+)
+
+// netlinkWatcher получает exec/exit от ядра через netlink proc connector.
+// Нужны права root (CAP_NET_ADMIN).
+type netlinkWatcher struct {
+	src  ProcessSource
+	proc string
+}
+
+func nativeProcessWatcher(src ProcessSource) ProcessWatcher {
+	return &netlinkWatcher{src: src, proc: "/proc"}
+}
+
+func (w *netlinkWatcher) Watch() (<-chan ProcessEvent, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %v", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %v", err)
+	}
+	if err := subscribe(fd); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("proc connector subscribe: %v", err)
+	}
+
+	events := make(chan ProcessEvent, 256)
+	go w.readLoop(fd, events)
+	return events, nil
+}
+
+// subscribe отправляет PROC_CN_MCAST_LISTEN: nlmsghdr + cn_msg + op
+func subscribe(fd int) error {
+	buf := make([]byte, unix.NLMSG_HDRLEN+cnMsgLen+4)
+	ne := binary.NativeEndian
+
+	ne.PutUint32(buf[0:], uint32(len(buf)))
+	ne.PutUint16(buf[4:], unix.NLMSG_DONE)
+
+	cn := buf[unix.NLMSG_HDRLEN:]
+	ne.PutUint32(cn[0:], cnIdxProc)
+	ne.PutUint32(cn[4:], cnValProc)
+	ne.PutUint16(cn[16:], 4)
+	ne.PutUint32(cn[cnMsgLen:], procCnMcastListen)
+
+	return unix.Sendto(fd, buf, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+}
+
+func (w *netlinkWatcher) readLoop(fd int, events chan<- ProcessEvent) {
+	defer close(events)
+	defer unix.Close(fd)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		if err == unix.ENOBUFS {
+			// Ядро выбросило часть событий; недостающее подберет контрольный опрос
+			log.Printf("Process events overflow, some events lost")
+			continue
+		}
+		if err != nil {
+			log.Printf("Process events read failed: %v", err)
+			return
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, m := range msgs {
+			if ev, ok := w.parse(m.Data); ok {
+				events <- ev
+			}
+		}
+	}
+}
+
+// parse разбирает struct proc_event после cn_msg:
+// what, cpu, timestamp_ns, затем pid/tgid процесса
+func (w *netlinkWatcher) parse(data []byte) (ProcessEvent, bool) {
+	if len(data) < cnMsgLen+24 {
+		return ProcessEvent{}, false
+	}
+	ne := binary.NativeEndian
+	ev := data[cnMsgLen:]
+	what := ne.Uint32(ev[0:])
+	pid := int32(ne.Uint32(ev[16:]))
+	tgid := int32(ne.Uint32(ev[20:]))
+
+	// Потоки не интересны, только основной поток процесса
+	if pid != tgid {
+		return ProcessEvent{}, false
+	}
+
+	now := time.Now()
+	switch what {
+	case procEventExec:
+		// Короткий процесс может завершиться, пока событие ждет в очереди:
+		// имя читается первым, пока запись в /proc еще есть (у зомби
+		// остается comm, но уже нет exe)
+		name := w.execName(pid)
+		if info, err := w.src.Process(pid); err == nil {
+			return ProcessEvent{Info: info, Time: now}, true
+		}
+		if name == "" {
+			return ProcessEvent{}, false
+		}
+		return ProcessEvent{Info: ProcessInfo{PID: tgid, Name: name, Created: now.UnixMilli()}, Time: now}, true
+	case procEventExit:
+		return ProcessEvent{Exit: true, Info: ProcessInfo{PID: pid}, Time: now}, true
+	}
+	return ProcessEvent{}, false
+}
+
+// execName — имя программы сразу после exec: файл из /proc/<pid>/exe,
+// иначе comm (обрезан ядром до 15 символов)
+func (w *netlinkWatcher) execName(pid int32) string {
+	dir := filepath.Join(w.proc, strconv.Itoa(int(pid)))
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		return filepath.Base(strings.TrimSuffix(exe, " (deleted)"))
+	}
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
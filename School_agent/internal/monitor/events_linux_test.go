@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// procEvent собирает cn_msg + proc_event с pid/tgid
+func procEvent(what uint32, pid int32) []byte {
+	data := make([]byte, cnMsgLen+24)
+	ne := binary.NativeEndian
+	ev := data[cnMsgLen:]
+	ne.PutUint32(ev[0:], what)
+	ne.PutUint32(ev[16:], uint32(pid))
+	ne.PutUint32(ev[20:], uint32(pid))
+	return data
+}
+
+// fakeProcDir создает /proc/<pid> с comm и, если задан, ссылкой exe
+func fakeProcDir(t *testing.T, root, pid, comm, exe string) {
+	t.Helper()
+	dir := filepath.Join(root, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exe != "" {
+		if err := os.Symlink(exe, filepath.Join(dir, "exe")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNetlinkExecOfExitedProcess(t *testing.T) {
+	src := NewFakeSource()
+	root := t.TempDir()
+	w := &netlinkWatcher{src: src, proc: root}
+
+	// Живой процесс берется у источника
+	src.Start(fakeProc(10, 1, "bash", 500))
+	ev, ok := w.parse(procEvent(procEventExec, 10))
+	if !ok || ev.Info.Name != "bash" || ev.Info.Created != 500 {
+		t.Fatalf("exec of running process: %+v, %v", ev, ok)
+	}
+
+	// Источник процесса уже не видит, но /proc еще читается: имя из exe
+	fakeProcDir(t, root, "20", "pwsh", "/opt/microsoft/powershell/7/pwsh")
+	ev, ok = w.parse(procEvent(procEventExec, 20))
+	if !ok || ev.Exit || ev.Info.PID != 20 || ev.Info.Name != "pwsh" || ev.Info.Created == 0 {
+		t.Fatalf("exec of exited process: %+v, %v", ev, ok)
+	}
+
+	// Зомби: exe уже нет, остается comm
+	fakeProcDir(t, root, "30", "powershell", "")
+	ev, ok = w.parse(procEvent(procEventExec, 30))
+	if !ok || ev.Info.Name != "powershell" {
+		t.Fatalf("exec of zombie: %+v, %v", ev, ok)
+	}
+
+	// Запись в /proc уже убрана: назвать процесс нечем
+	if ev, ok := w.parse(procEvent(procEventExec, 40)); ok {
+		t.Fatalf("exec of reaped process reported: %+v", ev)
+	}
+
+	if ev, ok := w.parse(procEvent(procEventExit, 20)); !ok || !ev.Exit || ev.Info.PID != 20 {
+		t.Fatalf("exit: %+v, %v", ev, ok)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package monitor
+
+import "errors"
+
+type noWatcher struct{}
+
+func nativeProcessWatcher(src ProcessSource) ProcessWatcher {
+	return noWatcher{}
+}
+
+func (noWatcher) Watch() (<-chan ProcessEvent, error) {
+	return nil, errors.New("process events are not supported on this OS")
+}
@@ -0,0 +1,189 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"time"
+
+	ole "github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+const (
+	wbemErrTimedOut = 0x80043001
+	// Разница между эпохой FILETIME (1601) и Unix в миллисекундах
+	filetimeEpochMs = 11644473600000
+)
+
+// wmiWatcher получает события ядра Win32_ProcessStartTrace/Win32_ProcessStopTrace
+// через WMI. Нужны права администратора (агент работает как служба).
+type wmiWatcher struct {
+	src ProcessSource
+}
+
+func nativeProcessWatcher(src ProcessSource) ProcessWatcher {
+	return &wmiWatcher{src: src}
+}
+
+func (w *wmiWatcher) Watch() (<-chan ProcessEvent, error) {
+	events := make(chan ProcessEvent, 256)
+	ready := make(chan error, 1)
+	go w.run(events, ready)
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (w *wmiWatcher) run(events chan<- ProcessEvent, ready chan<- error) {
+	defer close(events)
+
+	// COM-объекты можно использовать только из потока, в котором они созданы
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		// S_FALSE: COM в этом потоке уже инициализирован, это не ошибка
+		if oleErr, ok := err.(*ole.OleError); !ok || oleErr.Code() != 1 {
+			ready <- fmt.Errorf("CoInitializeEx: %v", err)
+			return
+		}
+	}
+	defer ole.CoUninitialize()
+
+	unknown, err := oleutil.CreateObject("WbemScripting.SWbemLocator")
+	if err != nil {
+		ready <- fmt.Errorf("SWbemLocator: %v", err)
+		return
+	}
+	defer unknown.Release()
+
+	locator, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		ready <- fmt.Errorf("SWbemLocator: %v", err)
+		return
+	}
+	defer locator.Release()
+
+	res, err := oleutil.CallMethod(locator, "ConnectServer", nil, `root\cimv2`)
+	if err != nil {
+		ready <- fmt.Errorf("WMI connect: %v", err)
+		return
+	}
+	service := res.ToIDispatch()
+	defer service.Release()
+
+	start, err := wmiSubscribe(service, "Win32_ProcessStartTrace")
+	if err != nil {
+		ready <- err
+		return
+	}
+	defer start.Release()
+
+	stop, err := wmiSubscribe(service, "Win32_ProcessStopTrace")
+	if err != nil {
+		ready <- err
+		return
+	}
+	defer stop.Release()
+
+	ready <- nil
+
+	// Подписки опрашиваются по очереди с коротким таймаутом:
+	// NextEvent блокирует поток до события или таймаута
+	for {
+		for _, sub := range []*ole.IDispatch{start, stop} {
+			ev, err := wmiNextEvent(sub, 250*time.Millisecond)
+			if err != nil {
+				log.Printf("Process events read failed: %v", err)
+				return
+			}
+			if ev == nil {
+				continue
+			}
+			events <- w.convert(ev, sub == stop)
+			ev.Release()
+		}
+	}
+}
+
+func (w *wmiWatcher) convert(ev *ole.IDispatch, exit bool) ProcessEvent {
+	now := time.Now()
+	pid := int32(wmiUint(ev, "ProcessID"))
+	if exit {
+		return ProcessEvent{Exit: true, Info: ProcessInfo{PID: pid}, Time: now}
+	}
+
+	// Время создания берется у источника, чтобы ключ процесса совпал
+	// с контрольным опросом; если процесс уже завершился — из события
+	if info, err := w.src.Process(pid); err == nil {
+		return ProcessEvent{Info: info, Time: now}
+	}
+	info := ProcessInfo{
+		PID:     pid,
+		PPID:    int32(wmiUint(ev, "ParentProcessID")),
+		Name:    wmiString(ev, "ProcessName"),
+		Created: now.UnixMilli(),
+	}
+	if ft := wmiUint(ev, "TIME_CREATED"); ft > 0 {
+		info.Created = int64(ft/10000) - filetimeEpochMs
+	}
+	return ProcessEvent{Info: info, Time: now}
+}
+
+func wmiSubscribe(service *ole.IDispatch, class string) (*ole.IDispatch, error) {
+	res, err := oleutil.CallMethod(service, "ExecNotificationQuery", "SELECT * FROM "+class)
+	if err != nil {
+		return nil, fmt.Errorf("WMI subscribe %s: %v", class, err)
+	}
+	return res.ToIDispatch(), nil
+}
+
+// wmiNextEvent ждет событие не дольше timeout; nil без ошибки — таймаут
+func wmiNextEvent(sub *ole.IDispatch, timeout time.Duration) (*ole.IDispatch, error) {
+	res, err := oleutil.CallMethod(sub, "NextEvent", int32(timeout/time.Millisecond))
+	if err != nil {
+		if oleErr, ok := err.(*ole.OleError); ok {
+			if info, ok := oleErr.SubError().(ole.EXCEPINFO); ok && info.SCODE() == wbemErrTimedOut {
+				return nil, nil
+			}
+		}
+		return nil, err
+	}
+	return res.ToIDispatch(), nil
+}
+
+func wmiString(obj *ole.IDispatch, name string) string {
+	v, err := oleutil.GetProperty(obj, name)
+	if err != nil {
+		return ""
+	}
+	defer v.Clear()
+	return v.ToString()
+}
+
+// wmiUint читает целое свойство; uint64 WMI передает строкой
+func wmiUint(obj *ole.IDispatch, name string) uint64 {
+	v, err := oleutil.GetProperty(obj, name)
+	if err != nil {
+		return 0
+	}
+	defer v.Clear()
+
+	switch x := v.Value().(type) {
+	case int32:
+		return uint64(uint32(x))
+	case uint32:
+		return uint64(x)
+	case int64:
+		return uint64(x)
+	case uint64:
+		return x
+	case string:
+		n, _ := strconv.ParseUint(x, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package monitor
+
+import (
+	"debug/buildinfo"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"school_agent/internal/models"
+)
+
+// readExeMetadata читает то, что есть в ELF: GNU build-id и путь модуля
+// у программ на Go. Ресурса версии в ELF нет, поэтому исходное имя
+// остается пустым: SONAME — имя библиотеки для компоновщика, а не файла.
+func readExeMetadata(path string) (models.ExeIdentity, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return models.ExeIdentity{}, err
+	}
+	defer f.Close()
+
+	var id models.ExeIdentity
+	if sec := f.Section(".note.gnu.build-id"); sec != nil {
+		if data, err := sec.Data(); err == nil {
+			id.BuildID = parseBuildID(data, f.ByteOrder)
+		}
+	}
+	if info, err := buildinfo.ReadFile(path); err == nil {
+		id.ProductName = info.Main.Path
+		id.FileVersion = info.Main.Version
+	}
+	return id, nil
+}
+
+// parseBuildID разбирает ELF note: namesz, descsz, type, имя "GNU\0", затем desc
+func parseBuildID(data []byte, order binary.ByteOrder) string {
+	if len(data) < 16 {
+		return ""
+	}
+	namesz := int(order.Uint32(data[0:]))
+	descsz := int(order.Uint32(data[4:]))
+	start := 12 + (namesz+3)&^3
+	if start+descsz > len(data) {
+		return ""
+	}
+	return hex.EncodeToString(data[start : start+descsz])
+}
@@ -0,0 +1,26 @@
+//go:build !windows
+
+package monitor
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadExeMetadataELF(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip(err)
+	}
+	id, err := readExeMetadata(exe)
+	if err != nil {
+		t.Fatalf("readExeMetadata: %v", err)
+	}
+	// Ресурса версии в ELF нет: исходное имя не выдумывается
+	if id.OriginalName != "" {
+		t.Errorf("OriginalName = %q, want empty", id.OriginalName)
+	}
+	if id.ProductName == "" {
+		t.Errorf("Go module path not read: %+v", id)
+	}
+}
@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"fmt"
+	"school_agent/internal/models"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// readExeMetadata читает ресурс версии (то, что видно в свойствах файла)
+func readExeMetadata(path string) (models.ExeIdentity, error) {
+	size, err := windows.GetFileVersionInfoSize(path, nil)
+	if err != nil {
+		return models.ExeIdentity{}, err
+	}
+	buf := make([]byte, size)
+	block := unsafe.Pointer(&buf[0])
+	if err := windows.GetFileVersionInfo(path, 0, size, block); err != nil {
+		return models.ExeIdentity{}, err
+	}
+
+	// Строки лежат в разделе первого языка из списка переводов;
+	// если списка нет — в стандартном английском/Unicode
+	prefix := `\StringFileInfo\040904b0\`
+	var trans unsafe.Pointer
+	var n uint32
+	if windows.VerQueryValue(block, `\VarFileInfo\Translation`, unsafe.Pointer(&trans), &n) == nil && n >= 4 {
+		lang := *(*[2]uint16)(trans)
+		prefix = fmt.Sprintf(`\StringFileInfo\%04x%04x\`, lang[0], lang[1])
+	}
+
+	str := func(name string) string {
+		var p unsafe.Pointer
+		var l uint32
+		if windows.VerQueryValue(block, prefix+name, unsafe.Pointer(&p), &l) != nil || l == 0 {
+			return ""
+		}
+		return windows.UTF16PtrToString((*uint16)(p))
+	}
+
+	return models.ExeIdentity{
+		OriginalName: str("OriginalFilename"),
+		ProductName:  str("ProductName"),
+		Company:      str("CompanyName"),
+		FileVersion:  str("FileVersion"),
+	}, nil
+}
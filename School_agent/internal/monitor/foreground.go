@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// ForegroundWindow — окно, которое сейчас в фокусе
+type ForegroundWindow struct {
+	PID   int32
+	App   string // имя exe
+	Title string
+}
+
+// ForegroundSource сообщает текущее активное окно. ok=false — фокуса нет
+// (заблокированный экран, рабочий стол без окон).
+type ForegroundSource interface {
+	Foreground() (w ForegroundWindow, ok bool, err error)
+}
+
+// ForegroundMonitor опрашивает источник и отдает отрезки фокуса:
+// какое приложение и окно были активны и сколько секунд.
+type ForegroundMonitor struct {
+	source   ForegroundSource
+	interval time.Duration
+	callback func(w ForegroundWindow, seconds float64)
+
+	mu      sync.Mutex
+	current ForegroundWindow
+	active  bool
+	since   time.Time
+}
+
+func NewForegroundMonitor(source ForegroundSource, interval time.Duration, callback func(w ForegroundWindow, seconds float64)) *ForegroundMonitor {
+	return &ForegroundMonitor{
+		source:   source,
+		interval: interval,
+		callback: callback,
+	}
+}
+
+func (fm *ForegroundMonitor) Start() {
+	go fm.monitorLoop()
+}
+
+func (fm *ForegroundMonitor) monitorLoop() {
+	ticker := time.NewTicker(fm.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		fm.poll(time.Now())
+	}
+}
+
+func (fm *ForegroundMonitor) poll(now time.Time) {
+	w, ok, err := fm.source.Foreground()
+	if err != nil {
+		log.Printf("Foreground window check failed: %v", err)
+		ok = false
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.active && ok && w == fm.current {
+		return
+	}
+
+	if fm.active {
+		fm.callback(fm.current, now.Sub(fm.since).Seconds())
+	}
+	fm.current, fm.active, fm.since = w, ok, now
+}
+
+// Flush закрывает текущий отрезок фокуса (выход пользователя, смена дня)
+func (fm *ForegroundMonitor) Flush(now time.Time) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	if fm.active {
+		fm.callback(fm.current, now.Sub(fm.since).Seconds())
+		fm.since = now
+	}
+}
@@ -0,0 +1,30 @@
+package monitor
+
+import "sync"
+
+// FakeForeground — управляемый вручную источник фокуса для тестов
+// и запуска агента вне Windows
+type FakeForeground struct {
+	mu     sync.Mutex
+	window ForegroundWindow
+	ok     bool
+}
+
+func (f *FakeForeground) Set(w ForegroundWindow) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.window, f.ok = w, true
+}
+
+// Clear — ни одно окно не в фокусе
+func (f *FakeForeground) Clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ok = false
+}
+
+func (f *FakeForeground) Foreground() (ForegroundWindow, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.window, f.ok, nil
+}
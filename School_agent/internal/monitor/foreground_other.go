@@ -0,0 +1,9 @@
+//go:build !windows
+
+package monitor
+
+// NewForegroundSource вне Windows возвращает FakeForeground без окон:
+// агент собирается и запускается на Linux без зависимости от X11
+func NewForegroundSource() ForegroundSource {
+	return &FakeForeground{}
+}
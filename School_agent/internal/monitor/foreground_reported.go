@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// ReportedForeground — активное окно, о котором сообщает помощник из сессии
+// пользователя. Служба работает в сессии 0 и сама не видит рабочий стол
+// ученика. Если отчетов нет дольше maxAge (помощник не запущен, экран
+// заблокирован), фокуса нет.
+type ReportedForeground struct {
+	maxAge time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	window ForegroundWindow
+	ok     bool
+	at     time.Time
+}
+
+func NewReportedForeground(maxAge time.Duration) *ReportedForeground {
+	return &ReportedForeground{maxAge: maxAge, now: time.Now}
+}
+
+// Report сохраняет очередной отчет помощника; ok=false — ни одно окно не в фокусе
+func (r *ReportedForeground) Report(w ForegroundWindow, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.window, r.ok, r.at = w, ok, r.now()
+}
+
+func (r *ReportedForeground) Foreground() (ForegroundWindow, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.ok || r.now().Sub(r.at) > r.maxAge {
+		return ForegroundWindow{}, false, nil
+	}
+	return r.window, true, nil
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+type focusSpan struct {
+	app     string
+	seconds float64
+}
+
+func newTestForeground() (*FakeForeground, *ForegroundMonitor, *[]focusSpan) {
+	src := &FakeForeground{}
+	var spans []focusSpan
+	fm := NewForegroundMonitor(src, time.Second, func(w ForegroundWindow, seconds float64) {
+		spans = append(spans, focusSpan{w.App, seconds})
+	})
+	return src, fm, &spans
+}
+
+func TestForegroundPollReportsFinishedSpans(t *testing.T) {
+	src, fm, spans := newTestForeground()
+	t0 := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	src.Set(ForegroundWindow{PID: 1, App: "WINWORD.EXE", Title: "Doc"})
+	fm.poll(t0)
+	fm.poll(t0.Add(5 * time.Second)) // то же окно — отрезок продолжается
+	if len(*spans) != 0 {
+		t.Fatalf("span reported while window unchanged: %v", *spans)
+	}
+
+	src.Set(ForegroundWindow{PID: 2, App: "chrome.exe", Title: "Search"})
+	fm.poll(t0.Add(10 * time.Second))
+
+	// Смена заголовка — новый отрезок того же приложения
+	src.Set(ForegroundWindow{PID: 2, App: "chrome.exe", Title: "Video"})
+	fm.poll(t0.Add(12 * time.Second))
+
+	src.Clear()
+	fm.poll(t0.Add(20 * time.Second))
+	fm.poll(t0.Add(30 * time.Second)) // без фокуса ничего не копится
+
+	want := []focusSpan{{"WINWORD.EXE", 10}, {"chrome.exe", 2}, {"chrome.exe", 8}}
+	if len(*spans) != len(want) {
+		t.Fatalf("spans = %v, want %v", *spans, want)
+	}
+	for i, s := range want {
+		if (*spans)[i] != s {
+			t.Errorf("span %d = %v, want %v", i, (*spans)[i], s)
+		}
+	}
+}
+
+func TestForegroundFlush(t *testing.T) {
+	src, fm, spans := newTestForeground()
+	t0 := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	fm.Flush(t0) // фокуса не было — нечего закрывать
+	if len(*spans) != 0 {
+		t.Fatalf("flush without focus reported %v", *spans)
+	}
+
+	src.Set(ForegroundWindow{PID: 1, App: "Code.exe"})
+	fm.poll(t0)
+	fm.Flush(t0.Add(7 * time.Second))
+	fm.poll(t0.Add(9 * time.Second)) // окно то же — отрезок после Flush идет дальше
+	fm.Flush(t0.Add(10 * time.Second))
+
+	want := []focusSpan{{"Code.exe", 7}, {"Code.exe", 3}}
+	if len(*spans) != len(want) || (*spans)[0] != want[0] || (*spans)[1] != want[1] {
+		t.Fatalf("spans = %v, want %v", *spans, want)
+	}
+}
+
+func TestReportedForegroundExpires(t *testing.T) {
+	now := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+	r := NewReportedForeground(3 * time.Second)
+	r.now = func() time.Time { return now }
+
+	if _, ok, _ := r.Foreground(); ok {
+		t.Fatal("focus reported before any helper report")
+	}
+
+	r.Report(ForegroundWindow{PID: 7, App: "steam.exe"}, true)
+	now = now.Add(2 * time.Second)
+	if w, ok, _ := r.Foreground(); !ok || w.App != "steam.exe" {
+		t.Fatalf("Foreground() = %v, %v; want steam.exe", w, ok)
+	}
+
+	now = now.Add(2 * time.Second) // помощник замолчал
+	if _, ok, _ := r.Foreground(); ok {
+		t.Fatal("stale report still treated as focus")
+	}
+
+	r.Report(ForegroundWindow{}, false)
+	if _, ok, _ := r.Foreground(); ok {
+		t.Fatal("report without window treated as focus")
+	}
+}
@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"unsafe"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                   = windows.NewLazySystemDLL("user32.dll")
+	procGetWindowTextW       = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW = user32.NewProc("GetWindowTextLengthW")
+)
+
+// windowsForeground читает активное окно через user32. Работает только
+// в интерактивной сессии пользователя, поэтому используется помощником
+// (userhelper), а служба получает окно через ReportedForeground.
+type windowsForeground struct{}
+
+func NewForegroundSource() ForegroundSource {
+	return windowsForeground{}
+}
+
+func (windowsForeground) Foreground() (ForegroundWindow, bool, error) {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return ForegroundWindow{}, false, nil
+	}
+
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil {
+		return ForegroundWindow{}, false, err
+	}
+
+	w := ForegroundWindow{PID: int32(pid), Title: windowText(hwnd)}
+	if p, err := process.NewProcess(int32(pid)); err == nil {
+		w.App, _ = p.Name()
+	}
+	return w, true, nil
+}
+
+func windowText(hwnd windows.HWND) string {
+	n, _, _ := procGetWindowTextLengthW.Call(uintptr(hwnd))
+	if n == 0 {
+		return ""
+	}
+	buf := make([]uint16, n+1)
+	procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return windows.UTF16ToString(buf)
+}
@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"school_agent/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+type hashEntry struct {
+	size    int64
+	modTime time.Time
+	sum     string
+	// Метаданные читаются отдельно от хеша и только по запросу
+	meta *models.ExeIdentity
+}
+
+// hashCache хранит SHA-256 и метаданные исполняемых файлов; запись
+// сбрасывается, если у файла поменялся размер или время изменения
+type hashCache struct {
+	mu      sync.Mutex
+	entries map[string]hashEntry
+}
+
+func newHashCache() *hashCache {
+	return &hashCache{entries: make(map[string]hashEntry)}
+}
+
+// entry возвращает актуальную запись кеша (возможно пустую) и данные файла
+func (c *hashCache) entry(path string) (hashEntry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return hashEntry{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || e.size != info.Size() || !e.modTime.Equal(info.ModTime()) {
+		e = hashEntry{size: info.Size(), modTime: info.ModTime()}
+		c.entries[path] = e
+	}
+	return e, nil
+}
+
+// update дописывает одно поле в текущую запись, если файл с тех пор не
+// менялся; остальные поля, заполненные параллельно, сохраняются
+func (c *hashCache) update(path string, e hashEntry, set func(cur *hashEntry)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cur, ok := c.entries[path]; ok && cur.size == e.size && cur.modTime.Equal(e.modTime) {
+		set(&cur)
+		c.entries[path] = cur
+	}
+}
+
+// SHA256 возвращает хеш файла; файл перечитывается, только если изменился
+func (c *hashCache) SHA256(path string) (string, error) {
+	e, err := c.entry(path)
+	if err != nil {
+		return "", err
+	}
+	if e.sum != "" {
+		return e.sum, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	c.update(path, e, func(cur *hashEntry) { cur.sum = sum })
+	return sum, nil
+}
+
+// Metadata возвращает встроенные метаданные файла без хеша
+func (c *hashCache) Metadata(path string) (models.ExeIdentity, error) {
+	e, err := c.entry(path)
+	if err != nil {
+		return models.ExeIdentity{}, err
+	}
+	if e.meta != nil {
+		return *e.meta, nil
+	}
+
+	// Файлы без ресурса версии тоже кешируются, с пустыми метаданными
+	meta, _ := readExeMetadata(path)
+	c.update(path, e, func(cur *hashEntry) { cur.meta = &meta })
+	return meta, nil
+}
+
+// Identity — хеш и метаданные файла вместе
+func (c *hashCache) Identity(path string) (models.ExeIdentity, error) {
+	id, err := c.Metadata(path)
+	if err != nil {
+		return id, err
+	}
+	id.SHA256, err = c.SHA256(path)
+	return id, err
+}
+
+// isRenamed сравнивает имя процесса с исходным именем файла без расширений
+// (".exe", ".mui" у локализованных ресурсов)
+func isRenamed(name string, id models.ExeIdentity) bool {
+	if id.OriginalName == "" {
+		return false
+	}
+	return !strings.EqualFold(trimExeExt(name), trimExeExt(id.OriginalName))
+}
+
+func trimExeExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".mui", ".exe"} {
+		lower = strings.TrimSuffix(lower, ext)
+	}
+	return lower
+}
@@ -0,0 +1,33 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"school_agent/internal/models"
+	"testing"
+)
+
+func TestHashCacheKeepsConcurrentFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.exe")
+	if err := os.WriteFile(path, []byte("binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := newHashCache()
+
+	// Метаданные читаются по записи, снятой до того, как посчитан хеш
+	stale, err := c.entry(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := c.SHA256(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta := models.ExeIdentity{ProductName: "Game"}
+	c.update(path, stale, func(cur *hashEntry) { cur.meta = &meta })
+
+	e, _ := c.entry(path)
+	if e.sum != sum || e.meta == nil || e.meta.ProductName != "Game" {
+		t.Fatalf("entry after concurrent updates: %+v", e)
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package monitor
+
+import "github.com/shirou/gopsutil/v3/process"
+
+// processParents строит карту PID -> PPID (на Linux — чтением /proc/<pid>/stat)
+func processParents() (map[int32]int32, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int32]int32, len(procs))
+	for _, p := range procs {
+		if ppid, err := p.Ppid(); err == nil {
+			parents[p.Pid] = ppid
+		}
+	}
+	return parents, nil
+}
@@ -0,0 +1,26 @@
+package monitor
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// processParents строит карту PID -> PPID одним снимком Toolhelp32:
+// Ppid() из gopsutil на Windows делает отдельный снимок на каждый процесс
+func processParents() (map[int32]int32, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snap)
+
+	parents := make(map[int32]int32)
+	var entry windows.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+
+	for err = windows.Process32First(snap, &entry); err == nil; err = windows.Process32Next(snap, &entry) {
+		parents[int32(entry.ProcessID)] = int32(entry.ParentProcessID)
+	}
+	return parents, nil
+}
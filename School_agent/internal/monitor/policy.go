@@ -0,0 +1,297 @@
+package monitor
+
+import (
+	"fmt"
+	"log"
+	"school_agent/internal/models"
+	"school_agent/internal/rules"
+	"strings"
+	"sync"
+	"time"
+)
+
+type policyRule struct {
+	models.PolicyRule
+	match *rules.Matcher
+	days  map[time.Weekday]bool
+}
+
+func (r *policyRule) active(now time.Time) bool {
+	if len(r.days) > 0 && !r.days[now.Weekday()] {
+		return false
+	}
+	if len(r.Schedule) == 0 {
+		return true
+	}
+	for _, tr := range r.Schedule {
+		if tr.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+type pendingKill struct {
+	key      procKey
+	rule     *policyRule
+	name     string
+	deadline time.Time
+}
+
+// ProcessPolicy — правила блокировки поверх ProcessMonitor: для подходящих
+// процессов пишет в лог, предупреждает пользователя или завершает процесс.
+// Каждое действие и его результат уходят в report.
+type ProcessPolicy struct {
+	// notify показывает предупреждение в оболочке владельца процесса
+	notify func(user, program, message string, countdown int) bool
+	report func(program, action string)
+
+	mu       sync.Mutex
+	version  string
+	rules    []*policyRule
+	groups   map[string]map[string]bool
+	username string
+
+	// Уже обработанные пары процесс/правило, чтобы не повторять предупреждения
+	handled map[procKey]map[string]bool
+	pending map[procKey]pendingKill
+}
+
+func NewProcessPolicy(notify func(user, program, message string, countdown int) bool, report func(program, action string)) *ProcessPolicy {
+	return &ProcessPolicy{
+		notify:  notify,
+		report:  report,
+		groups:  make(map[string]map[string]bool),
+		handled: make(map[procKey]map[string]bool),
+		pending: make(map[procKey]pendingKill),
+	}
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Apply заменяет правила. При ошибке продолжают действовать прежние.
+func (pp *ProcessPolicy) Apply(p models.Policy) error {
+	var list []*policyRule
+	for i, r := range p.Rules {
+		switch r.Action {
+		case models.PolicyLog, models.PolicyWarn, models.PolicyTerminate:
+		default:
+			return fmt.Errorf("rule %d: unknown action %q", i, r.Action)
+		}
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("rule-%d", i)
+		}
+
+		match, err := rules.Compile(r.Match)
+		if err != nil {
+			return fmt.Errorf("rule %s: %v", r.ID, err)
+		}
+
+		days := make(map[time.Weekday]bool)
+		for _, d := range r.Days {
+			wd, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return fmt.Errorf("rule %s: unknown day %q", r.ID, d)
+			}
+			days[wd] = true
+		}
+
+		list = append(list, &policyRule{PolicyRule: r, match: match, days: days})
+	}
+
+	groups := make(map[string]map[string]bool)
+	for g, users := range p.UserGroups {
+		groups[g] = make(map[string]bool)
+		for _, u := range users {
+			groups[g][bareUser(u)] = true
+		}
+	}
+
+	pp.mu.Lock()
+	pp.version = p.Version
+	pp.rules = list
+	pp.groups = groups
+	pp.handled = make(map[procKey]map[string]bool)
+	pp.pending = make(map[procKey]pendingKill)
+	pp.mu.Unlock()
+
+	log.Printf("Process policy %q applied: %d rules", p.Version, len(list))
+	return nil
+}
+
+func (pp *ProcessPolicy) Version() string {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	return pp.version
+}
+
+func (pp *ProcessPolicy) UpdateUsername(username string) {
+	pp.mu.Lock()
+	defer pp.mu.Unlock()
+	pp.username = bareUser(username)
+}
+
+// exempt — владелец процесса входит в группу, на которую правило не распространяется
+func (pp *ProcessPolicy) exempt(r *policyRule, owner string) bool {
+	for _, g := range r.ExemptGroups {
+		if pp.groups[g][owner] {
+			return true
+		}
+	}
+	return false
+}
+
+// policyAction — сработавшее правило; выполняется после снятия pp.mu,
+// потому что уведомление оболочки и завершение процесса могут идти долго
+type policyAction struct {
+	rule  *policyRule
+	key   procKey
+	name  string
+	owner string
+}
+
+// check вызывается монитором для каждого запущенного процесса. Правила
+// действуют только на процессы пользователя за консолью.
+func (pp *ProcessPolicy) check(src ProcessSource, info ProcessInfo, hashes *hashCache, now time.Time) {
+	pp.mu.Lock()
+	actions := pp.match(src, info, hashes, now)
+	pp.mu.Unlock()
+
+	for _, a := range actions {
+		pp.enforce(src, a)
+	}
+}
+
+func (pp *ProcessPolicy) match(src ProcessSource, info ProcessInfo, hashes *hashCache, now time.Time) []policyAction {
+	key, name := info.key(), info.Name
+	var actions []policyAction
+	owner, ownerKnown := "", false
+
+	for _, r := range pp.rules {
+		if pp.handled[key][r.ID] || !r.active(now) {
+			continue
+		}
+		if !matchProcess(src, r.match, info.PID, name, hashes) {
+			continue
+		}
+
+		// Владелец нужен только подошедшим по имени процессам
+		if !ownerKnown {
+			owner, _ = src.Username(info.PID)
+			owner = bareUser(owner)
+			ownerKnown = true
+		}
+		if owner == "" || owner != pp.username {
+			return actions
+		}
+		if pp.exempt(r, owner) {
+			continue
+		}
+
+		if pp.handled[key] == nil {
+			pp.handled[key] = make(map[string]bool)
+		}
+		pp.handled[key][r.ID] = true
+		if r.Action == models.PolicyTerminate && r.WarnSeconds > 0 {
+			pp.pending[key] = pendingKill{
+				key:      key,
+				rule:     r,
+				name:     name,
+				deadline: now.Add(time.Duration(r.WarnSeconds) * time.Second),
+			}
+		}
+		actions = append(actions, policyAction{rule: r, key: key, name: name, owner: owner})
+	}
+	return actions
+}
+
+func (pp *ProcessPolicy) enforce(src ProcessSource, a policyAction) {
+	r, pid, name := a.rule, a.key.pid, a.name
+	message := r.Message
+	if message == "" {
+		message = fmt.Sprintf("Программа %s запрещена во время уроков", name)
+	}
+
+	switch r.Action {
+	case models.PolicyLog:
+		pp.reportf(name, r, "detected (PID %d)", pid)
+
+	case models.PolicyWarn:
+		pp.reportf(name, r, "warned (PID %d, %s)", pid, pp.deliver(a.owner, name, message, 0))
+
+	case models.PolicyTerminate:
+		if r.WarnSeconds <= 0 {
+			pp.terminate(src, r, a.key, name)
+			return
+		}
+		pp.reportf(name, r, "warned, terminating in %ds (PID %d, %s)", r.WarnSeconds, pid, pp.deliver(a.owner, name, message, r.WarnSeconds))
+	}
+}
+
+func (pp *ProcessPolicy) deliver(owner, name, message string, countdown int) string {
+	if pp.notify != nil && pp.notify(owner, name, message, countdown) {
+		return "shell notified"
+	}
+	return "shell not connected"
+}
+
+// sweep завершает процессы с истекшим отсчетом и забывает закрытые процессы
+func (pp *ProcessPolicy) sweep(src ProcessSource, alive map[procKey]bool, now time.Time) {
+	var closed, skipped, due []pendingKill
+
+	pp.mu.Lock()
+	for key, k := range pp.pending {
+		if !alive[key] {
+			closed = append(closed, k)
+			delete(pp.pending, key)
+			continue
+		}
+		if now.Before(k.deadline) {
+			continue
+		}
+		delete(pp.pending, key)
+		if !k.rule.active(now) {
+			skipped = append(skipped, k)
+			continue
+		}
+		due = append(due, k)
+	}
+
+	for key := range pp.handled {
+		if !alive[key] {
+			delete(pp.handled, key)
+		}
+	}
+	pp.mu.Unlock()
+
+	for _, k := range closed {
+		pp.reportf(k.name, k.rule, "closed by user before termination (PID %d)", k.key.pid)
+	}
+	for _, k := range skipped {
+		pp.reportf(k.name, k.rule, "termination skipped, schedule ended (PID %d)", k.key.pid)
+	}
+	for _, k := range due {
+		pp.terminate(src, k.rule, k.key, k.name)
+	}
+}
+
+func (pp *ProcessPolicy) terminate(src ProcessSource, r *policyRule, key procKey, name string) {
+	pid := key.pid
+	// Источник проверяет время создания: если PID уже занят другим процессом, его не трогаем
+	if err := src.Kill(pid, key.created); err != nil {
+		pp.reportf(name, r, "termination failed (PID %d): %v", pid, err)
+		return
+	}
+	pp.reportf(name, r, "terminated (PID %d)", pid)
+}
+
+func (pp *ProcessPolicy) reportf(name string, r *policyRule, format string, args ...interface{}) {
+	action := fmt.Sprintf("Policy %s: %s", r.ID, fmt.Sprintf(format, args...))
+	log.Printf("%s: %s", name, action)
+	if pp.report != nil {
+		pp.report(name, action)
+	}
+}
@@ -2,21 +2,115 @@ package monitor
 
 import (
 	"log"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/shirou/gopsutil/v3/process"
 )
 
+// procKey идентифицирует экземпляр процесса: Windows переиспользует PID,
+// поэтому вместе с ним учитывается время создания (мс Unix)
+type procKey struct {
+	pid     int32
+	created int64
+}
+
+// trackedProcess — отслеживаемый процесс и метаданные, собранные при его запуске
+type trackedProcess struct {
+	name    string
+	details models.ProcessDetails
+}
+
 type ProcessMonitor struct {
-	processes map[int32]string
-	callback  func(action, program string)
+	source    ProcessSource
+	processes map[procKey]trackedProcess
+	callback  func(action, program string, details *models.ProcessDetails)
+	// Процессы, по которым уже отправлен алерт правил дерева
+	alerted   map[procKey]bool
+	treeAlert func(ruleID, program string, details *models.ProcessDetails)
+	// Дерево процессов: строится опросом, дополняется событиями
+	tree *procTree
+
+	watcher ProcessWatcher
+	resync  time.Duration
+
+	onlyConsoleUser bool
+	details         *detailsCollector
+
+	mu        sync.Mutex
+	watchlist *watchlist
+	policy    *ProcessPolicy
+	hashes    *hashCache
+	username  string
+}
+
+func NewProcessMonitor(cfg config.ProcessConfig, source ProcessSource, callback func(action, program string, details *models.ProcessDetails)) *ProcessMonitor {
+	hashes := newHashCache()
+	pm := &ProcessMonitor{
+		source:          source,
+		processes:       make(map[procKey]trackedProcess),
+		callback:        callback,
+		alerted:         make(map[procKey]bool),
+		tree:            newProcTree(),
+		resync:          time.Duration(cfg.ResyncSec) * time.Second,
+		onlyConsoleUser: cfg.OnlyConsoleUser,
+		details:         newDetailsCollector(cfg, hashes),
+		watchlist:       emptyWatchlist(),
+		hashes:          hashes,
+	}
+	if cfg.Events {
+		pm.watcher = newProcessWatcher(source)
+	}
+	if pm.resync <= 0 {
+		pm.resync = time.Minute
+	}
+	return pm
+}
+
+// UpdateUsername задает пользователя консоли для режима OnlyConsoleUser
+func (pm *ProcessMonitor) UpdateUsername(username string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.username = username
 }
 
-func NewProcessMonitor(callback func(action, program string)) *ProcessMonitor {
-	return &ProcessMonitor{
-		processes: make(map[int32]string),
-		callback:  callback,
+// SetWatchlist заменяет правила отслеживания. При ошибке в правилах
+// продолжает действовать предыдущий список.
+func (pm *ProcessMonitor) SetWatchlist(wl models.Watchlist) error {
+	compiled, err := compileWatchlist(wl)
+	if err != nil {
+		return err
 	}
+
+	pm.mu.Lock()
+	pm.watchlist = compiled
+	pm.mu.Unlock()
+
+	log.Printf("Process watchlist %q applied: %d rules, %d tree rules, all user processes: %v", wl.Version, len(wl.Rules), len(wl.TreeRules), wl.AllUserProcesses)
+	return nil
+}
+
+// AttachPolicy подключает правила блокировки; они проверяются
+// для всех процессов, а не только для отслеживаемых
+func (pm *ProcessMonitor) AttachPolicy(policy *ProcessPolicy) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.policy = policy
+}
+
+// SetTreeAlertHandler задает обработчик правил дерева процессов с действием alert
+func (pm *ProcessMonitor) SetTreeAlertHandler(handler func(ruleID, program string, details *models.ProcessDetails)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.treeAlert = handler
+}
+
+// WatchlistVersion возвращает версию действующего списка
+func (pm *ProcessMonitor) WatchlistVersion() string {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return pm.watchlist.version
 }
 
 func (pm *ProcessMonitor) Start() {
@@ -24,82 +118,330 @@ func (pm *ProcessMonitor) Start() {
 }
 
 func (pm *ProcessMonitor) monitorLoop() {
+	var events <-chan ProcessEvent
+	if pm.watcher != nil {
+		ch, err := pm.watcher.Watch()
+		if err != nil {
+			log.Printf("Process events unavailable, polling every 3s: %v", err)
+		} else {
+			log.Printf("Process events enabled, resync every %s", pm.resync)
+			events = ch
+		}
+	}
+
+	// Исходное состояние: события приходят только о новых процессах
+	pm.checkProcesses()
+	lastPoll := time.Now()
+
 	ticker := time.NewTicker(3 * time.Second)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		pm.checkProcesses()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				log.Printf("Process events stopped, polling every 3s")
+				events = nil
+				continue
+			}
+			if ev.Exit {
+				pm.processExited(ev.Info.PID, ev.Time)
+			} else {
+				pm.processStarted(ev.Info, ev.Time)
+			}
+
+		case now := <-ticker.C:
+			// С событиями полный опрос только сверяет состояние на случай
+			// потерянных событий, а отсчеты политики идут по таймеру
+			if events == nil || now.Sub(lastPoll) >= pm.resync {
+				pm.checkProcesses()
+				lastPoll = now
+			} else if policy := pm.settings().policy; policy != nil {
+				policy.sweep(pm.source, pm.tree.alive(), now)
+			}
+		}
 	}
 }
 
+// monitorSettings — правила, действующие на момент обработки
+type monitorSettings struct {
+	wl        *watchlist
+	policy    *ProcessPolicy
+	username  string
+	treeAlert func(ruleID, program string, details *models.ProcessDetails)
+}
+
+func (pm *ProcessMonitor) settings() monitorSettings {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	return monitorSettings{
+		wl:        pm.watchlist,
+		policy:    pm.policy,
+		username:  pm.username,
+		treeAlert: pm.treeAlert,
+	}
+}
+
+// treeMatch — процесс, для которого сработало правило дерева с действием alert
+type treeMatch struct {
+	rule string
+	proc trackedProcess
+}
+
 func (pm *ProcessMonitor) checkProcesses() {
-	currentProcs := make(map[int32]string)
-	
-	procs, err := process.Processes()
+	currentProcs := make(map[procKey]trackedProcess)
+
+	procs, err := pm.source.Processes()
 	if err != nil {
+		log.Printf("Process list failed: %v", err)
 		return
 	}
 
-	for _, p := range procs {
-		name, err := p.Name()
-		if err != nil {
-			continue
+	st := pm.settings()
+	now := time.Now()
+	tree := newProcTree()
+
+	// Первый проход: дерево процессов и политика
+	for _, info := range procs {
+		tree.add(info)
+
+		if st.policy != nil {
+			st.policy.check(pm.source, info, pm.hashes, now)
 		}
+	}
 
-		if pm.isImportantProcess(name) {
-			currentProcs[p.Pid] = name
+	// Второй проход: новые процессы с учетом их предков
+	var opened []procKey
+	var alerts []treeMatch
+	alerted := make(map[procKey]bool)
 
-			if _, exists := pm.processes[p.Pid]; !exists {
-				pm.callback("Opened", name)
-				log.Printf("Process started: %s (PID: %d)", name, p.Pid)
-			}
+	for _, info := range procs {
+		key := info.key()
+		if pm.alerted[key] {
+			alerted[key] = true
+		}
+		// Имя меняется после exec на Linux: это уже другая программа
+		if t, exists := pm.processes[key]; exists && t.name == info.Name {
+			currentProcs[key] = t
+			continue
+		}
+
+		t, track, alert := pm.inspect(st, info, tree, alerted)
+		if alert != nil {
+			alerted[key] = true
+			alerts = append(alerts, *alert)
+		}
+		if track {
+			currentProcs[key] = t
+			opened = append(opened, key)
 		}
 	}
 
-	for pid, name := range pm.processes {
-		if _, exists := currentProcs[pid]; !exists {
-			pm.callback("Closed", name)
-			log.Printf("Process ended: %s (PID: %d)", name, pid)
+	// Сначала закрытия, потом запуски: если PID успели переиспользовать,
+	// старый процесс закрывается раньше, чем открывается новый
+	for key, t := range pm.processes {
+		if _, exists := currentProcs[key]; !exists {
+			pm.closeSession(t, now)
 		}
 	}
 
+	for _, key := range opened {
+		pm.announce(currentProcs[key])
+	}
+
+	for _, a := range alerts {
+		pm.raiseAlert(st, a)
+	}
+
+	pm.mu.Lock()
 	pm.processes = currentProcs
+	pm.mu.Unlock()
+	pm.alerted = alerted
+	pm.tree = tree
+
+	if st.policy != nil {
+		st.policy.sweep(pm.source, tree.alive(), now)
+	}
+}
+
+// processStarted обрабатывает событие запуска без полного опроса
+func (pm *ProcessMonitor) processStarted(info ProcessInfo, now time.Time) {
+	st := pm.settings()
+	key := info.key()
+	pm.tree.add(info)
+
+	if st.policy != nil {
+		st.policy.check(pm.source, info, pm.hashes, now)
+	}
+
+	// Выход прежнего владельца PID мог потеряться, а exec на Linux
+	// меняет программу у того же процесса
+	for old, t := range pm.processes {
+		if old.pid != key.pid {
+			continue
+		}
+		if old == key && t.name == info.Name {
+			return
+		}
+		pm.forget(old)
+		pm.closeSession(t, now)
+	}
+
+	t, track, alert := pm.inspect(st, info, pm.tree, pm.alerted)
+	if track {
+		pm.mu.Lock()
+		pm.processes[key] = t
+		pm.mu.Unlock()
+		pm.announce(t)
+	}
+	if alert != nil {
+		pm.alerted[key] = true
+		pm.raiseAlert(st, *alert)
+	}
+}
+
+// processExited закрывает сессию по событию завершения
+func (pm *ProcessMonitor) processExited(pid int32, now time.Time) {
+	pm.tree.remove(pid)
+	for key, t := range pm.processes {
+		if key.pid == pid {
+			pm.forget(key)
+			pm.closeSession(t, now)
+		}
+	}
+	for key := range pm.alerted {
+		if key.pid == pid {
+			delete(pm.alerted, key)
+		}
+	}
+}
+
+func (pm *ProcessMonitor) forget(key procKey) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	delete(pm.processes, key)
+}
+
+// inspect решает судьбу еще не отслеживаемого процесса: начать ли его
+// отслеживать и сработало ли на нем правило дерева с действием alert
+func (pm *ProcessMonitor) inspect(st monitorSettings, info ProcessInfo, tree *procTree, alerted map[procKey]bool) (trackedProcess, bool, *treeMatch) {
+	rule := pm.matchTreeRule(st.wl, info, tree)
+	if rule != nil && rule.Action == models.TreeIgnore {
+		return trackedProcess{}, false, nil
+	}
+
+	alert := rule != nil && rule.Action == models.TreeAlert && !alerted[info.key()]
+	important := pm.isImportantProcess(st.wl, info.PID, info.Name)
+	if !alert && !important {
+		return trackedProcess{}, false, nil
+	}
+
+	t := trackedProcess{name: info.Name, details: pm.details.collect(pm.source, info, tree)}
+	var match *treeMatch
+	if alert {
+		match = &treeMatch{rule: rule.ID, proc: t}
+	}
+
+	if !important || (pm.onlyConsoleUser && !sameUser(t.details.Owner, st.username)) {
+		return trackedProcess{}, false, match
+	}
+	return t, true, match
+}
+
+func (pm *ProcessMonitor) announce(t trackedProcess) {
+	pm.callback("Opened", t.name, &t.details)
+	log.Printf("Process started: %s (PID: %d, parents: %s)", t.name, t.details.PID, strings.Join(t.details.Ancestry, " < "))
+	if t.details.Renamed {
+		log.Printf("Process %s (PID: %d) is renamed %s", t.name, t.details.PID, t.details.Identity.OriginalName)
+	}
+}
+
+func (pm *ProcessMonitor) raiseAlert(st monitorSettings, a treeMatch) {
+	d := a.proc.details
+	log.Printf("Process tree rule %s: %s (PID: %d) started by %s", a.rule, a.proc.name, d.PID, strings.Join(d.Ancestry, " < "))
+	if st.treeAlert != nil {
+		st.treeAlert(a.rule, a.proc.name, &d)
+	}
+}
+
+// matchTreeRule возвращает первое сработавшее правило дерева процессов
+func (pm *ProcessMonitor) matchTreeRule(wl *watchlist, info ProcessInfo, tree *procTree) *treeRule {
+	if len(wl.tree) == 0 {
+		return nil
+	}
+	chain := tree.ancestors(info.PID)
+	for _, r := range wl.tree {
+		if r.matches(pm.source, info, chain, pm.hashes) {
+			return r
+		}
+	}
+	return nil
+}
+
+// closeSession отправляет Closed с временем жизни процесса. При опросе точное
+// время выхода неизвестно, за конец сессии берется момент, когда процесс пропал из списка.
+func (pm *ProcessMonitor) closeSession(t trackedProcess, now time.Time) {
+	details := t.details
+	details.EndTime = &now
+	if !details.StartTime.IsZero() {
+		details.DurationSec = now.Sub(details.StartTime).Seconds()
+	}
+
+	pm.callback("Closed", t.name, &details)
+	log.Printf("Process ended: %s (PID: %d, %s)", t.name, details.PID, time.Duration(details.DurationSec*float64(time.Second)).Round(time.Second))
+}
+
+// Terminate завершает отслеживаемые процессы приложений пользователя
+// (дневные лимиты). Возвращает число завершенных процессов.
+func (pm *ProcessMonitor) Terminate(user string, apps []string) int {
+	names := make(map[string]bool, len(apps))
+	for _, app := range apps {
+		names[strings.ToLower(app)] = true
+	}
+
+	pm.mu.Lock()
+	var keys []procKey
+	for key, t := range pm.processes {
+		if names[strings.ToLower(t.name)] && sameUser(t.details.Owner, user) {
+			keys = append(keys, key)
+		}
+	}
+	pm.mu.Unlock()
+
+	killed := 0
+	for _, key := range keys {
+		if err := pm.source.Kill(key.pid, key.created); err != nil {
+			log.Printf("Failed to terminate PID %d: %v", key.pid, err)
+			continue
+		}
+		killed++
+	}
+	return killed
 }
 
-func (pm *ProcessMonitor) isImportantProcess(name string) bool {
-	important := map[string]bool{
-		"chrome.exe":          true,
-		"msedge.exe":          true,
-		"firefox.exe":         true,
-		"Code.exe":            true,
-		"notepad.exe":         true,
-		"notepad++.exe":       true,
-		"WINWORD.EXE":         true,
-		"EXCEL.EXE":           true,
-		"POWERPNT.EXE":        true,
-		"AcroRd32.exe":        true,
-		"Acrobat.exe":         true,
-		"PhotoshopCC.exe":     true,
-		"Photoshop.exe":       true,
-		"Illustrator.exe":     true,
-		"vlc.exe":             true,
-		"steam.exe":           true,
-		"Discord.exe":         true,
-		"Telegram.exe":        true,
-		"Spotify.exe":         true,
-		"cmd.exe":             true,
-		"powershell.exe":      true,
-		"python.exe":          true,
-		"java.exe":            true,
-		"javaw.exe":           true,
-		"node.exe":            true,
-		"git.exe":             true,
-		"VisualStudio.exe":    true,
-		"devenv.exe":          true,
-		"Slack.exe":           true,
-		"Teams.exe":           true,
-		"Zoom.exe":            true,
-	}
-
-	return important[name]
+// isTracked сообщает, отслеживается ли сейчас экземпляр процесса
+func (pm *ProcessMonitor) isTracked(key procKey) bool {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	_, ok := pm.processes[key]
+	return ok
+}
+
+// isKnown — процесс явно указан в правилах списка (режим "все процессы" не учитывается)
+func (pm *ProcessMonitor) isKnown(pid int32, name string) bool {
+	pm.mu.Lock()
+	wl := pm.watchlist
+	pm.mu.Unlock()
+
+	return matchProcess(pm.source, wl.match, pid, name, pm.hashes)
+}
+
+func (pm *ProcessMonitor) isImportantProcess(wl *watchlist, pid int32, name string) bool {
+	if wl.allUsers {
+		username, err := pm.source.Username(pid)
+		if err == nil && !isSystemAccount(username) {
+			return true
+		}
+	}
+	return matchProcess(pm.source, wl.match, pid, name, pm.hashes)
 }
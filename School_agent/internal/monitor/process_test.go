@@ -0,0 +1,286 @@
+package monitor
+
+import (
+	"fmt"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+type processRecord struct {
+	action string
+	name   string
+	pid    int32
+	start  time.Time
+}
+
+func (r processRecord) String() string {
+	return fmt.Sprintf("%s %s/%d@%d", r.action, r.name, r.pid, r.start.UnixMilli())
+}
+
+type treeAlertRecord struct {
+	rule     string
+	name     string
+	ancestry []string
+}
+
+type testMonitor struct {
+	src     *FakeSource
+	pm      *ProcessMonitor
+	records []processRecord
+	alerts  []treeAlertRecord
+}
+
+func newTestMonitor(t *testing.T, wl models.Watchlist) *testMonitor {
+	t.Helper()
+	tm := &testMonitor{src: NewFakeSource()}
+	tm.pm = NewProcessMonitor(config.ProcessConfig{CommandLine: config.CommandLineNone}, tm.src, func(action, program string, d *models.ProcessDetails) {
+		tm.records = append(tm.records, processRecord{action, program, d.PID, d.StartTime})
+	})
+	tm.pm.SetTreeAlertHandler(func(ruleID, program string, d *models.ProcessDetails) {
+		tm.alerts = append(tm.alerts, treeAlertRecord{ruleID, program, d.Ancestry})
+	})
+	if err := tm.pm.SetWatchlist(wl); err != nil {
+		t.Fatalf("SetWatchlist: %v", err)
+	}
+	return tm
+}
+
+// take возвращает записи с прошлого вызова
+func (tm *testMonitor) take() string {
+	var out []string
+	for _, r := range tm.records {
+		out = append(out, r.String())
+	}
+	tm.records = nil
+	return strings.Join(out, ", ")
+}
+
+func fakeProc(pid, ppid int32, name string, created int64) FakeProcess {
+	return FakeProcess{
+		ProcessInfo: ProcessInfo{PID: pid, PPID: ppid, Name: name, Created: created},
+		Username:    "pupil",
+	}
+}
+
+func names(patterns ...string) []models.WatchRule {
+	var rules []models.WatchRule
+	for _, p := range patterns {
+		rules = append(rules, models.WatchRule{Type: models.RuleName, Pattern: p})
+	}
+	return rules
+}
+
+func TestProcessMonitorPIDReuse(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{Rules: names("game.exe")})
+
+	tm.src.Start(fakeProc(100, 1, "game.exe", 1000))
+	tm.pm.checkProcesses()
+	if got, want := tm.take(), "Opened game.exe/100@1000"; got != want {
+		t.Fatalf("first poll: got %q, want %q", got, want)
+	}
+
+	// Выход пропущен, PID занят новым экземпляром той же программы
+	tm.src.Start(fakeProc(100, 1, "game.exe", 2000))
+	tm.pm.checkProcesses()
+	if got, want := tm.take(), "Closed game.exe/100@1000, Opened game.exe/100@2000"; got != want {
+		t.Fatalf("poll after PID reuse: got %q, want %q", got, want)
+	}
+
+	// То же по событию запуска без события выхода
+	now := time.Now()
+	tm.pm.processStarted(ProcessInfo{PID: 100, PPID: 1, Name: "game.exe", Created: 3000}, now)
+	if got, want := tm.take(), "Closed game.exe/100@2000, Opened game.exe/100@3000"; got != want {
+		t.Fatalf("start event after PID reuse: got %q, want %q", got, want)
+	}
+
+	tm.pm.processExited(100, now)
+	if got, want := tm.take(), "Closed game.exe/100@3000"; got != want {
+		t.Fatalf("exit event: got %q, want %q", got, want)
+	}
+}
+
+func TestProcessMonitorExecRename(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{Rules: names("game.exe")})
+
+	tm.src.Start(fakeProc(200, 1, "sh", 1000))
+	tm.pm.checkProcesses()
+	if got := tm.take(); got != "" {
+		t.Fatalf("untracked process reported: %q", got)
+	}
+
+	// exec: тот же PID и время создания, другая программа
+	tm.src.Start(fakeProc(200, 1, "game.exe", 1000))
+	tm.pm.checkProcesses()
+	if got, want := tm.take(), "Opened game.exe/200@1000"; got != want {
+		t.Fatalf("after exec: got %q, want %q", got, want)
+	}
+
+	tm.pm.processStarted(ProcessInfo{PID: 200, PPID: 1, Name: "python3", Created: 1000}, time.Now())
+	if got, want := tm.take(), "Closed game.exe/200@1000"; got != want {
+		t.Fatalf("after second exec: got %q, want %q", got, want)
+	}
+}
+
+func TestProcessMonitorTreeRules(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{
+		Rules: names("game.exe"),
+		TreeRules: []models.TreeRule{
+			{ID: "office-shell", Process: names("cmd.exe"), Parent: names("winword.exe"), AnyAncestor: true, Action: models.TreeAlert},
+			{ID: "launcher", Process: names("game.exe"), Parent: names("steam.exe"), Action: models.TreeIgnore},
+		},
+	})
+
+	tm.src.Start(fakeProc(1, 0, "explorer.exe", 100))
+	tm.src.Start(fakeProc(10, 1, "winword.exe", 200))
+	tm.src.Start(fakeProc(11, 10, "conhost.exe", 300))
+	tm.src.Start(fakeProc(12, 11, "cmd.exe", 400))
+	tm.src.Start(fakeProc(20, 1, "steam.exe", 500))
+	tm.src.Start(fakeProc(21, 20, "game.exe", 600))
+	tm.src.Start(fakeProc(30, 1, "game.exe", 700))
+	tm.pm.checkProcesses()
+
+	// Игра из steam проигнорирована, запущенная из проводника — отслеживается
+	if got, want := tm.take(), "Opened game.exe/30@700"; got != want {
+		t.Fatalf("tracked: got %q, want %q", got, want)
+	}
+	if len(tm.alerts) != 1 {
+		t.Fatalf("alerts: got %v, want one", tm.alerts)
+	}
+	a := tm.alerts[0]
+	if a.rule != "office-shell" || a.name != "cmd.exe" || strings.Join(a.ancestry, " < ") != "conhost.exe < winword.exe < explorer.exe" {
+		t.Fatalf("alert: got %+v", a)
+	}
+
+	// Повторный опрос не повторяет алерт
+	tm.pm.checkProcesses()
+	if len(tm.alerts) != 1 {
+		t.Fatalf("alert repeated: %v", tm.alerts)
+	}
+
+	// Родитель с переиспользованным PID моложе ребенка — не предок
+	tm.src.Start(fakeProc(40, 41, "cmd.exe", 800))
+	tm.src.Start(fakeProc(41, 1, "winword.exe", 900))
+	tm.pm.checkProcesses()
+	if len(tm.alerts) != 1 {
+		t.Fatalf("alert through reused parent PID: %v", tm.alerts)
+	}
+}
+
+func TestProcessPolicyKillChecksCreated(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{})
+	var reports []string
+	policy := NewProcessPolicy(nil, func(program, action string) {
+		reports = append(reports, action)
+	})
+	policy.UpdateUsername("pupil")
+	if err := policy.Apply(models.Policy{Rules: []models.PolicyRule{
+		{ID: "no-games", Match: names("game.exe"), Action: models.PolicyTerminate, WarnSeconds: 30},
+	}}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	tm.pm.AttachPolicy(policy)
+
+	tm.src.Start(fakeProc(100, 1, "game.exe", 1000))
+	tm.pm.checkProcesses()
+	stale := procKey{pid: 100, created: 1000}
+	if _, ok := policy.pending[stale]; !ok {
+		t.Fatalf("termination not scheduled, reports: %v", reports)
+	}
+
+	// К концу отсчета PID занят другим процессом, а снимок еще старый
+	tm.src.Start(fakeProc(100, 1, "notepad.exe", 5000))
+	policy.sweep(tm.src, map[procKey]bool{stale: true}, time.Now().Add(time.Minute))
+
+	if killed := tm.src.Killed(); len(killed) != 0 {
+		t.Fatalf("killed %v after PID reuse", killed)
+	}
+	if last := reports[len(reports)-1]; !strings.Contains(last, "termination failed") {
+		t.Fatalf("last report: %q", last)
+	}
+	if _, err := tm.src.Process(100); err != nil {
+		t.Fatalf("new owner of PID 100 is gone: %v", err)
+	}
+
+	// Без отсчета совпадающий экземпляр завершается сразу
+	if err := policy.Apply(models.Policy{Rules: []models.PolicyRule{
+		{ID: "no-games", Match: names("game.exe"), Action: models.PolicyTerminate},
+	}}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	tm.src.Start(fakeProc(200, 1, "game.exe", 6000))
+	tm.pm.checkProcesses()
+	if killed := tm.src.Killed(); len(killed) != 1 || killed[0] != 200 {
+		t.Fatalf("killed: got %v, want [200]", killed)
+	}
+}
+
+func TestFakeSourceEmitOutsideLock(t *testing.T) {
+	src := NewFakeSource()
+	if _, err := src.Watch(); err != nil {
+		t.Fatal(err)
+	}
+	// Заполняем буфер событий, следующий запуск ждет читателя
+	for pid := int32(1); pid <= 256; pid++ {
+		src.Start(fakeProc(pid, 0, "a.exe", 1))
+	}
+	go src.Start(fakeProc(1000, 0, "b.exe", 1))
+
+	done := make(chan struct{})
+	go func() {
+		src.Processes()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("source locked while an event is waiting for a reader")
+	}
+}
+
+func TestProcessPolicyChecksOwner(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{})
+	var notified []string
+	policy := NewProcessPolicy(func(user, program, message string, countdown int) bool {
+		notified = append(notified, user+" "+program)
+		return true
+	}, nil)
+	if err := policy.Apply(models.Policy{
+		Rules: []models.PolicyRule{
+			{ID: "no-games", Match: names("game.exe"), Action: models.PolicyWarn, ExemptGroups: []string{"teachers"}},
+		},
+		UserGroups: map[string][]string{"teachers": {"SCHOOL\\Teacher"}},
+	}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	policy.UpdateUsername("pupil")
+	tm.pm.AttachPolicy(policy)
+
+	other := fakeProc(10, 1, "game.exe", 1000)
+	other.Username = "SCHOOL\\teacher"
+	tm.src.Start(other)
+	system := fakeProc(11, 1, "game.exe", 1000)
+	system.Username = "NT AUTHORITY\\SYSTEM"
+	tm.src.Start(system)
+	own := fakeProc(12, 1, "game.exe", 1000)
+	own.Username = "SCHOOL\\Pupil"
+	tm.src.Start(own)
+	tm.pm.checkProcesses()
+
+	if len(notified) != 1 || notified[0] != "pupil game.exe" {
+		t.Fatalf("warnings: got %v, want one to the owner of the console user's process", notified)
+	}
+
+	// Учитель за консолью освобожден группой, в которой записан с доменом
+	notified = nil
+	policy.UpdateUsername("teacher")
+	teacher := fakeProc(20, 1, "game.exe", 2000)
+	teacher.Username = "teacher"
+	tm.src.Start(teacher)
+	tm.pm.checkProcesses()
+	if len(notified) != 0 {
+		t.Fatalf("exempt owner warned: %v", notified)
+	}
+}
@@ -0,0 +1,106 @@
+package monitor
+
+import (
+	"log"
+	"regexp"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"strings"
+	"time"
+)
+
+// detailsCollector собирает метаданные процесса с учетом настроек приватности
+type detailsCollector struct {
+	cmdMode string
+	redact  []*regexp.Regexp
+	// nil — хеш и метаданные exe не собираются
+	identities *hashCache
+}
+
+func newDetailsCollector(cfg config.ProcessConfig, hashes *hashCache) *detailsCollector {
+	c := &detailsCollector{cmdMode: cfg.CommandLine}
+	if cfg.Identity {
+		c.identities = hashes
+	}
+	for _, pattern := range cfg.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("Bad redact pattern %q: %v", pattern, err)
+			continue
+		}
+		c.redact = append(c.redact, re)
+	}
+	return c
+}
+
+// collect собирает метаданные; родитель и предки берутся из дерева текущего опроса
+func (c *detailsCollector) collect(src ProcessSource, info ProcessInfo, tree *procTree) models.ProcessDetails {
+	d := models.ProcessDetails{PID: info.PID, ParentPID: info.PPID}
+
+	d.Exe, _ = src.Exe(info.PID)
+	d.Owner, _ = src.Username(info.PID)
+	d.SessionID, _ = src.Session(info.PID)
+
+	if info.Created > 0 {
+		d.StartTime = time.UnixMilli(info.Created)
+	}
+
+	if chain := tree.ancestors(info.PID); len(chain) > 0 {
+		d.ParentName = chain[0].name
+		d.Ancestry = ancestryNames(chain)
+	}
+
+	if c.identities != nil && d.Exe != "" {
+		if id, err := c.identities.Identity(d.Exe); err == nil {
+			d.Identity = &id
+			d.Renamed = isRenamed(info.Name, id)
+		}
+	}
+
+	if c.cmdMode != config.CommandLineNone {
+		if cmdline, err := src.Cmdline(info.PID); err == nil {
+			d.CommandLine = c.commandLine(cmdline, d.Exe)
+		}
+	}
+	return d
+}
+
+func (c *detailsCollector) commandLine(cmdline, exe string) string {
+	switch c.cmdMode {
+	case config.CommandLineFull:
+		return cmdline
+	case config.CommandLineExeOnly:
+		return exe
+	default:
+		for _, re := range c.redact {
+			cmdline = re.ReplaceAllStringFunc(cmdline, redactMatch(re))
+		}
+		return cmdline
+	}
+}
+
+// redactMatch прячет совпадение под ***. Если в шаблоне есть первая группа,
+// она сохраняется: "(password=)\S+" превращает "password=123" в "password=***"
+func redactMatch(re *regexp.Regexp) func(string) string {
+	return func(m string) string {
+		sub := re.FindStringSubmatch(m)
+		if len(sub) > 1 && strings.HasPrefix(m, sub[1]) {
+			return sub[1] + "***"
+		}
+		return "***"
+	}
+}
+
+// sameUser сравнивает владельца процесса ("DOMAIN\user") с пользователем консоли
+func sameUser(owner, username string) bool {
+	owner = bareUser(owner)
+	return owner != "" && owner == bareUser(username)
+}
+
+// bareUser — имя пользователя без домена в нижнем регистре
+func bareUser(name string) string {
+	if idx := strings.LastIndex(name, "\\"); idx != -1 {
+		name = name[idx+1:]
+	}
+	return strings.ToLower(name)
+}
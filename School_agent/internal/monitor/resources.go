@@ -0,0 +1,271 @@
+package monitor
+
+import (
+	"log"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+type resourceSample struct {
+	at       time.Time
+	cpu      float64
+	memoryMB float64
+}
+
+// procResources — история замеров одного экземпляра процесса
+type procResources struct {
+	name      string
+	exe       string
+	startTime time.Time
+	known     bool
+
+	lastAt     time.Time
+	lastCPU    float64 // суммарное время CPU, сек
+	lastIO     uint64  // прочитано + записано, байт
+	samples    []resourceSample
+	stats      models.ResourceStats
+	breachFrom map[string]time.Time
+	alerted    map[string]bool
+}
+
+// ResourceMonitor периодически снимает CPU, память, диск и потоки процессов,
+// держит скользящую статистику за окно и поднимает алерты при превышении порогов.
+type ResourceMonitor struct {
+	cfg     config.ResourceConfig
+	procMon *ProcessMonitor
+	source  ProcessSource
+	alert   func(a models.ResourceAlert)
+
+	mu    sync.Mutex
+	procs map[procKey]*procResources
+	// Системные процессы, которые не нужно проверять заново на каждом замере
+	skip map[procKey]bool
+}
+
+func NewResourceMonitor(cfg config.ResourceConfig, procMon *ProcessMonitor, alert func(a models.ResourceAlert)) *ResourceMonitor {
+	return &ResourceMonitor{
+		cfg:     cfg,
+		procMon: procMon,
+		source:  procMon.source,
+		alert:   alert,
+		procs:   make(map[procKey]*procResources),
+		skip:    make(map[procKey]bool),
+	}
+}
+
+func (rm *ResourceMonitor) Start() {
+	go rm.monitorLoop()
+}
+
+func (rm *ResourceMonitor) monitorLoop() {
+	interval := time.Duration(rm.cfg.IntervalSec) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rm.sample(time.Now())
+	}
+}
+
+func (rm *ResourceMonitor) sample(now time.Time) {
+	procs, err := rm.source.Processes()
+	if err != nil {
+		return
+	}
+
+	// Алерты уходят после снятия блокировки: обработчик шлет их по сети
+	var alerts []models.ResourceAlert
+	defer func() {
+		for _, a := range alerts {
+			rm.alert(a)
+		}
+	}()
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	seen := make(map[procKey]bool)
+	for _, info := range procs {
+		key := info.key()
+		seen[key] = true
+		if rm.skip[key] {
+			continue
+		}
+
+		pr, ok := rm.procs[key]
+		if !ok {
+			pr = rm.newProc(info)
+			if pr == nil {
+				continue
+			}
+			rm.procs[key] = pr
+		}
+		rm.measure(info.PID, pr, now)
+		alerts = append(alerts, rm.checkAlerts(pr, now)...)
+	}
+
+	for key := range rm.procs {
+		if !seen[key] {
+			delete(rm.procs, key)
+		}
+	}
+	for key := range rm.skip {
+		if !seen[key] {
+			delete(rm.skip, key)
+		}
+	}
+}
+
+// newProc решает, нужно ли следить за процессом; nil — не нужно
+func (rm *ResourceMonitor) newProc(info ProcessInfo) *procResources {
+	key := info.key()
+	if rm.cfg.Mode == config.ResourcesTracked {
+		if !rm.procMon.isTracked(key) {
+			return nil
+		}
+	} else {
+		owner, err := rm.source.Username(info.PID)
+		if err != nil || isSystemAccount(owner) {
+			rm.skip[key] = true
+			return nil
+		}
+	}
+
+	pr := &procResources{
+		name:       info.Name,
+		known:      rm.procMon.isKnown(info.PID, info.Name),
+		breachFrom: make(map[string]time.Time),
+		alerted:    make(map[string]bool),
+	}
+	pr.exe, _ = rm.source.Exe(info.PID)
+	if info.Created > 0 {
+		pr.startTime = time.UnixMilli(info.Created)
+	}
+	return pr
+}
+
+func (rm *ResourceMonitor) measure(pid int32, pr *procResources, now time.Time) {
+	s := &pr.stats
+	s.PID, s.Name, s.Exe, s.StartTime = pid, pr.name, pr.exe, pr.startTime
+
+	// Процесс мог завершиться после снимка: замер пропускается
+	usage, err := rm.source.Usage(pid)
+	if err != nil {
+		return
+	}
+	cpuTotal, ioTotal := usage.CPUSeconds, usage.IOBytes
+
+	if !pr.lastAt.IsZero() {
+		wall := now.Sub(pr.lastAt).Seconds()
+		if wall > 0 {
+			// В процентах одного ядра: 100 — ядро занято полностью
+			s.CPU = (cpuTotal - pr.lastCPU) / wall * 100
+			if ioTotal >= pr.lastIO {
+				s.DiskKBps = float64(ioTotal-pr.lastIO) / 1024 / wall
+			}
+		}
+	}
+	pr.lastAt, pr.lastCPU, pr.lastIO = now, cpuTotal, ioTotal
+
+	s.MemoryMB = float64(usage.RSSBytes) / 1024 / 1024
+	s.Threads = usage.Threads
+
+	// Скользящее окно
+	pr.samples = append(pr.samples, resourceSample{at: now, cpu: s.CPU, memoryMB: s.MemoryMB})
+	window := time.Duration(rm.cfg.WindowSec) * time.Second
+	for len(pr.samples) > 1 && now.Sub(pr.samples[0].at) > window {
+		pr.samples = pr.samples[1:]
+	}
+
+	s.CPUAvg, s.CPUMax, s.MemMaxMB = 0, 0, 0
+	for _, smp := range pr.samples {
+		s.CPUAvg += smp.cpu
+		if smp.cpu > s.CPUMax {
+			s.CPUMax = smp.cpu
+		}
+		if smp.memoryMB > s.MemMaxMB {
+			s.MemMaxMB = smp.memoryMB
+		}
+	}
+	s.CPUAvg /= float64(len(pr.samples))
+	s.Samples = len(pr.samples)
+}
+
+func metricValue(s models.ResourceStats, metric string) float64 {
+	switch metric {
+	case models.MetricCPU:
+		return s.CPU
+	case models.MetricMemoryMB:
+		return s.MemoryMB
+	case models.MetricDiskKBps:
+		return s.DiskKBps
+	case models.MetricThreads:
+		return float64(s.Threads)
+	}
+	return 0
+}
+
+// checkAlerts возвращает алерты, если порог превышен непрерывно ForSec секунд.
+// Повторно по тому же правилу — только после того, как значение опустится ниже порога.
+func (rm *ResourceMonitor) checkAlerts(pr *procResources, now time.Time) []models.ResourceAlert {
+	if pr.stats.Samples < 2 {
+		return nil
+	}
+
+	var alerts []models.ResourceAlert
+	for _, r := range rm.cfg.Alerts {
+		if r.UnknownOnly && pr.known {
+			continue
+		}
+
+		value := metricValue(pr.stats, r.Metric)
+		if value <= r.Above {
+			delete(pr.breachFrom, r.ID)
+			pr.alerted[r.ID] = false
+			continue
+		}
+
+		from, ok := pr.breachFrom[r.ID]
+		if !ok {
+			pr.breachFrom[r.ID] = now
+			from = now
+		}
+		if pr.alerted[r.ID] || now.Sub(from) < time.Duration(r.ForSec)*time.Second {
+			continue
+		}
+
+		pr.alerted[r.ID] = true
+		log.Printf("Resource alert %s: %s (PID %d) %s=%.1f > %.1f for %ds", r.ID, pr.name, pr.stats.PID, r.Metric, value, r.Above, r.ForSec)
+		alerts = append(alerts, models.ResourceAlert{
+			Rule:      r.ID,
+			Metric:    r.Metric,
+			Value:     value,
+			Threshold: r.Above,
+			ForSec:    r.ForSec,
+			Process:   pr.stats,
+		})
+	}
+	return alerts
+}
+
+// Top возвращает n процессов с наибольшей средней загрузкой CPU
+func (rm *ResourceMonitor) Top(n int) []models.ResourceStats {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	list := make([]models.ResourceStats, 0, len(rm.procs))
+	for _, pr := range rm.procs {
+		list = append(list, pr.stats)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CPUAvg > list[j].CPUAvg })
+	if n > 0 && len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
@@ -0,0 +1,43 @@
+package monitor
+
+import (
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"testing"
+	"time"
+)
+
+func TestResourceMonitorCPUPerCore(t *testing.T) {
+	tm := newTestMonitor(t, models.Watchlist{})
+	var alerts []models.ResourceAlert
+	var rm *ResourceMonitor
+	rm = NewResourceMonitor(config.ResourceConfig{
+		Mode:      config.ResourcesAllUser,
+		WindowSec: 60,
+		Alerts: []models.ResourceAlertRule{
+			{ID: "cpu-unknown", Metric: models.MetricCPU, Above: 80, ForSec: 10, UnknownOnly: true},
+		},
+	}, tm.pm, func(a models.ResourceAlert) {
+		// Обработчик вызывается без блокировки монитора и может к нему обращаться
+		rm.Top(1)
+		alerts = append(alerts, a)
+	})
+
+	tm.src.Start(fakeProc(100, 1, "miner.exe", 1000))
+	now := time.Unix(1000, 0)
+	rm.sample(now)
+
+	// Одно ядро занято полностью: секунда CPU на секунду времени
+	for i := 1; i <= 20; i++ {
+		tm.src.SetUsage(100, ProcessUsage{CPUSeconds: float64(i)})
+		rm.sample(now.Add(time.Duration(i) * time.Second))
+	}
+
+	top := rm.Top(1)
+	if len(top) != 1 || top[0].CPU < 99.9 || top[0].CPU > 100.1 {
+		t.Fatalf("CPU of one busy core: got %+v, want 100", top)
+	}
+	if len(alerts) != 1 || alerts[0].Process.PID != 100 {
+		t.Fatalf("alerts: got %+v, want one for PID 100", alerts)
+	}
+}
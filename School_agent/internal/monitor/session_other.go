@@ -0,0 +1,11 @@
+//go:build !windows
+
+package monitor
+
+import "golang.org/x/sys/unix"
+
+// processSessionID вне Windows возвращает SID сессии процесса
+func processSessionID(pid int32) (uint32, error) {
+	sid, err := unix.Getsid(int(pid))
+	return uint32(sid), err
+}
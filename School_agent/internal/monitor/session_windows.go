@@ -0,0 +1,10 @@
+package monitor
+
+import "golang.org/x/sys/windows"
+
+// processSessionID возвращает номер сессии терминальных служб процесса
+func processSessionID(pid int32) (uint32, error) {
+	var sid uint32
+	err := windows.ProcessIdToSessionId(uint32(pid), &sid)
+	return sid, err
+}
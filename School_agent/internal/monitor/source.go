@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"log"
+	"school_agent/internal/config"
+)
+
+// ProcessInfo — процесс в снимке ProcessSource
+type ProcessInfo struct {
+	PID     int32
+	PPID    int32
+	Name    string
+	Created int64 // время создания, мс Unix
+}
+
+// ProcessUsage — накопленные счетчики ресурсов процесса для ResourceMonitor
+type ProcessUsage struct {
+	CPUSeconds float64 // user + system, сек
+	IOBytes    uint64  // прочитано + записано
+	RSSBytes   uint64
+	Threads    int32
+}
+
+func (i ProcessInfo) key() procKey {
+	return procKey{pid: i.PID, created: i.Created}
+}
+
+// ProcessSource — откуда ProcessMonitor берет список процессов и их атрибуты.
+// Атрибуты запрашиваются по PID лениво: большинству процессов хватает имени.
+type ProcessSource interface {
+	Processes() ([]ProcessInfo, error)
+	// Process — один процесс по PID, для событий запуска
+	Process(pid int32) (ProcessInfo, error)
+	Exe(pid int32) (string, error)
+	Cmdline(pid int32) (string, error)
+	Username(pid int32) (string, error)
+	// Session — номер сессии терминальных служб на Windows, SID сессии на Unix
+	Session(pid int32) (uint32, error)
+	// Usage — счетчики CPU, памяти, диска и потоков. Недоступные
+	// счетчики (нет прав) остаются нулевыми
+	Usage(pid int32) (ProcessUsage, error)
+	// Kill завершает процесс, только если PID все еще принадлежит
+	// экземпляру, созданному в created
+	Kill(pid int32, created int64) error
+}
+
+// NewProcessSource выбирает источник по настройке process.source
+func NewProcessSource(kind string) ProcessSource {
+	switch kind {
+	case config.ProcessSourceGopsutil:
+		return NewGopsutilSource()
+	case config.ProcessSourceProcfs, config.ProcessSourceAuto, "":
+		if src := nativeProcessSource(); src != nil {
+			return src
+		}
+		if kind == config.ProcessSourceProcfs {
+			log.Printf("Process source %q is not available on this OS, using gopsutil", kind)
+		}
+		return NewGopsutilSource()
+	default:
+		log.Printf("Unknown process source %q, using gopsutil", kind)
+		return NewGopsutilSource()
+	}
+}
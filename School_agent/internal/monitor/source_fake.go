@@ -0,0 +1,149 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FakeProcess — процесс в FakeSource
+type FakeProcess struct {
+	ProcessInfo
+	Exe       string
+	Cmdline   string
+	Username  string
+	SessionID uint32
+	Usage     ProcessUsage
+}
+
+// FakeSource — управляемый вручную список процессов для тестов:
+// запуски, выходы и переиспользование PID задаются явно. После Watch
+// каждый запуск и выход дополнительно приходит событием.
+type FakeSource struct {
+	mu     sync.Mutex
+	procs  map[int32]FakeProcess
+	killed []int32
+	events chan ProcessEvent
+}
+
+func NewFakeSource() *FakeSource {
+	return &FakeSource{procs: make(map[int32]FakeProcess)}
+}
+
+// Start добавляет процесс; процесс с тем же PID заменяется (PID переиспользован)
+func (f *FakeSource) Start(p FakeProcess) {
+	f.mu.Lock()
+	f.procs[p.PID] = p
+	f.mu.Unlock()
+	f.emit(ProcessEvent{Info: p.ProcessInfo, Time: time.Now()})
+}
+
+func (f *FakeSource) Exit(pid int32) {
+	f.mu.Lock()
+	delete(f.procs, pid)
+	f.mu.Unlock()
+	f.emit(ProcessEvent{Exit: true, Info: ProcessInfo{PID: pid}, Time: time.Now()})
+}
+
+// SetUsage задает счетчики ресурсов запущенного процесса
+func (f *FakeSource) SetUsage(pid int32, u ProcessUsage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if p, ok := f.procs[pid]; ok {
+		p.Usage = u
+		f.procs[pid] = p
+	}
+}
+
+func (f *FakeSource) Watch() (<-chan ProcessEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.events == nil {
+		f.events = make(chan ProcessEvent, 256)
+	}
+	return f.events, nil
+}
+
+// emit вызывается без f.mu: читатель событий обращается к источнику,
+// и отправка в заполненный канал под блокировкой его бы заблокировала
+func (f *FakeSource) emit(ev ProcessEvent) {
+	f.mu.Lock()
+	events := f.events
+	f.mu.Unlock()
+	if events != nil {
+		events <- ev
+	}
+}
+
+// Killed — PID процессов, завершенных через Kill
+func (f *FakeSource) Killed() []int32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]int32(nil), f.killed...)
+}
+
+func (f *FakeSource) Processes() ([]ProcessInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	infos := make([]ProcessInfo, 0, len(f.procs))
+	for _, p := range f.procs {
+		infos = append(infos, p.ProcessInfo)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].PID < infos[j].PID })
+	return infos, nil
+}
+
+func (f *FakeSource) get(pid int32) (FakeProcess, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.procs[pid]
+	if !ok {
+		return FakeProcess{}, fmt.Errorf("process %d not found", pid)
+	}
+	return p, nil
+}
+
+func (f *FakeSource) Process(pid int32) (ProcessInfo, error) {
+	p, err := f.get(pid)
+	return p.ProcessInfo, err
+}
+
+func (f *FakeSource) Exe(pid int32) (string, error) {
+	p, err := f.get(pid)
+	return p.Exe, err
+}
+
+func (f *FakeSource) Cmdline(pid int32) (string, error) {
+	p, err := f.get(pid)
+	return p.Cmdline, err
+}
+
+func (f *FakeSource) Username(pid int32) (string, error) {
+	p, err := f.get(pid)
+	return p.Username, err
+}
+
+func (f *FakeSource) Session(pid int32) (uint32, error) {
+	p, err := f.get(pid)
+	return p.SessionID, err
+}
+
+func (f *FakeSource) Usage(pid int32) (ProcessUsage, error) {
+	p, err := f.get(pid)
+	return p.Usage, err
+}
+
+func (f *FakeSource) Kill(pid int32, created int64) error {
+	f.mu.Lock()
+	p, ok := f.procs[pid]
+	if !ok || p.Created != created {
+		f.mu.Unlock()
+		return fmt.Errorf("process exited, PID reused")
+	}
+	delete(f.procs, pid)
+	f.killed = append(f.killed, pid)
+	f.mu.Unlock()
+	f.emit(ProcessEvent{Exit: true, Info: ProcessInfo{PID: pid}, Time: time.Now()})
+	return nil
+}
@@ -0,0 +1,113 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// GopsutilSource — ProcessSource поверх gopsutil, работает на любой ОС
+type GopsutilSource struct{}
+
+func NewGopsutilSource() *GopsutilSource {
+	return &GopsutilSource{}
+}
+
+func keyOf(p *process.Process) procKey {
+	created, _ := p.CreateTime()
+	return procKey{pid: p.Pid, created: created}
+}
+
+func (s *GopsutilSource) Processes() ([]ProcessInfo, error) {
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	parents, err := processParents()
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ProcessInfo, 0, len(procs))
+	for _, p := range procs {
+		name, err := p.Name()
+		if err != nil {
+			continue
+		}
+		created, _ := p.CreateTime()
+		infos = append(infos, ProcessInfo{PID: p.Pid, PPID: parents[p.Pid], Name: name, Created: created})
+	}
+	return infos, nil
+}
+
+func (s *GopsutilSource) Process(pid int32) (ProcessInfo, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	name, err := p.Name()
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	ppid, _ := p.Ppid()
+	created, _ := p.CreateTime()
+	return ProcessInfo{PID: pid, PPID: ppid, Name: name, Created: created}, nil
+}
+
+func (s *GopsutilSource) Exe(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	return p.Exe()
+}
+
+func (s *GopsutilSource) Cmdline(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	return p.Cmdline()
+}
+
+func (s *GopsutilSource) Username(pid int32) (string, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return "", err
+	}
+	return p.Username()
+}
+
+func (s *GopsutilSource) Session(pid int32) (uint32, error) {
+	return processSessionID(pid)
+}
+
+func (s *GopsutilSource) Usage(pid int32) (ProcessUsage, error) {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return ProcessUsage{}, err
+	}
+	var u ProcessUsage
+	if t, err := p.Times(); err == nil {
+		u.CPUSeconds = t.User + t.System
+	}
+	if io, err := p.IOCounters(); err == nil {
+		u.IOBytes = io.ReadBytes + io.WriteBytes
+	}
+	if mem, err := p.MemoryInfo(); err == nil {
+		u.RSSBytes = mem.RSS
+	}
+	u.Threads, _ = p.NumThreads()
+	return u, nil
+}
+
+func (s *GopsutilSource) Kill(pid int32, created int64) error {
+	p, err := process.NewProcess(pid)
+	if err != nil {
+		return err
+	}
+	if keyOf(p) != (procKey{pid: pid, created: created}) {
+		return fmt.Errorf("process exited, PID reused")
+	}
+	return p.Kill()
+}
@@ -0,0 +1,223 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// Тиков в секунду в /proc/<pid>/stat (USER_HZ)
+const procClockTicks = 100
+
+// ProcfsSource читает процессы напрямую из /proc: один файл stat на процесс
+// вместо нескольких запросов gopsutil
+type ProcfsSource struct {
+	root     string
+	bootTime int64
+
+	mu    sync.Mutex
+	users map[string]string
+}
+
+func nativeProcessSource() ProcessSource {
+	return NewProcfsSource("/proc")
+}
+
+func NewProcfsSource(root string) *ProcfsSource {
+	// Время загрузки берется у gopsutil, чтобы время создания процессов
+	// совпадало с тем, что видит монитор ресурсов
+	boot, _ := host.BootTime()
+	return &ProcfsSource{
+		root:     root,
+		bootTime: int64(boot),
+		users:    make(map[string]string),
+	}
+}
+
+func (s *ProcfsSource) Processes() ([]ProcessInfo, error) {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]ProcessInfo, 0, len(entries))
+	for _, e := range entries {
+		pid, err := strconv.ParseInt(e.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		// Процесс мог завершиться между ReadDir и чтением stat
+		if info, err := s.Process(int32(pid)); err == nil {
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (s *ProcfsSource) Process(pid int32) (ProcessInfo, error) {
+	comm, fields, err := s.stat(pid)
+	if err != nil {
+		return ProcessInfo{}, err
+	}
+	ppid, _ := strconv.ParseInt(fields[statPPID], 10, 32)
+	start, _ := strconv.ParseUint(fields[statStartTime], 10, 64)
+
+	return ProcessInfo{
+		PID:     pid,
+		PPID:    int32(ppid),
+		Name:    s.name(pid, comm),
+		Created: s.bootTime*1000 + int64(start*1000/procClockTicks),
+	}, nil
+}
+
+// Номера полей /proc/<pid>/stat после имени процесса (man 5 proc, поле N — индекс N-3)
+const (
+	statPPID       = 1
+	statSession    = 3
+	statUTime      = 11
+	statSTime      = 12
+	statThreads    = 17
+	statStartTime  = 19
+	statRSS        = 21
+	statFieldCount = 22
+)
+
+// stat разбирает /proc/<pid>/stat. Имя в скобках может содержать пробелы
+// и скобки, поэтому поля считаются от последней ")"
+func (s *ProcfsSource) stat(pid int32) (string, []string, error) {
+	data, err := os.ReadFile(s.path(pid, "stat"))
+	if err != nil {
+		return "", nil, err
+	}
+	open := bytes.IndexByte(data, '(')
+	closing := bytes.LastIndexByte(data, ')')
+	if open < 0 || closing < open {
+		return "", nil, fmt.Errorf("bad stat format for PID %d", pid)
+	}
+	fields := strings.Fields(string(data[closing+1:]))
+	if len(fields) < statFieldCount {
+		return "", nil, fmt.Errorf("bad stat format for PID %d", pid)
+	}
+	return string(data[open+1 : closing]), fields, nil
+}
+
+// name: comm в stat обрезан до 15 символов, полное имя берется из cmdline
+func (s *ProcfsSource) name(pid int32, comm string) string {
+	if len(comm) < 15 {
+		return comm
+	}
+	cmdline, err := os.ReadFile(s.path(pid, "cmdline"))
+	if err != nil || len(cmdline) == 0 {
+		return comm
+	}
+	argv0 := string(bytes.SplitN(cmdline, []byte{0}, 2)[0])
+	if base := filepath.Base(argv0); strings.HasPrefix(base, comm) {
+		return base
+	}
+	return comm
+}
+
+func (s *ProcfsSource) Exe(pid int32) (string, error) {
+	return os.Readlink(s.path(pid, "exe"))
+}
+
+func (s *ProcfsSource) Cmdline(pid int32) (string, error) {
+	data, err := os.ReadFile(s.path(pid, "cmdline"))
+	if err != nil {
+		return "", err
+	}
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	return strings.Join(args, " "), nil
+}
+
+func (s *ProcfsSource) Username(pid int32) (string, error) {
+	data, err := os.ReadFile(s.path(pid, "status"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "Uid:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+		return s.lookupUser(fields[1])
+	}
+	return "", fmt.Errorf("no Uid for PID %d", pid)
+}
+
+func (s *ProcfsSource) lookupUser(uid string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name, ok := s.users[uid]; ok {
+		return name, nil
+	}
+	u, err := user.LookupId(uid)
+	if err != nil {
+		return "", err
+	}
+	s.users[uid] = u.Username
+	return u.Username, nil
+}
+
+func (s *ProcfsSource) Session(pid int32) (uint32, error) {
+	_, fields, err := s.stat(pid)
+	if err != nil {
+		return 0, err
+	}
+	sid, err := strconv.ParseUint(fields[statSession], 10, 32)
+	return uint32(sid), err
+}
+
+func (s *ProcfsSource) Usage(pid int32) (ProcessUsage, error) {
+	_, fields, err := s.stat(pid)
+	if err != nil {
+		return ProcessUsage{}, err
+	}
+	utime, _ := strconv.ParseUint(fields[statUTime], 10, 64)
+	stime, _ := strconv.ParseUint(fields[statSTime], 10, 64)
+	threads, _ := strconv.ParseInt(fields[statThreads], 10, 32)
+	rss, _ := strconv.ParseUint(fields[statRSS], 10, 64)
+
+	u := ProcessUsage{
+		CPUSeconds: float64(utime+stime) / procClockTicks,
+		RSSBytes:   rss * uint64(os.Getpagesize()),
+		Threads:    int32(threads),
+	}
+	// io читается только владельцем процесса или root
+	if data, err := os.ReadFile(s.path(pid, "io")); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			name, value, ok := strings.Cut(line, ":")
+			if ok && (name == "read_bytes" || name == "write_bytes") {
+				n, _ := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+				u.IOBytes += n
+			}
+		}
+	}
+	return u, nil
+}
+
+func (s *ProcfsSource) Kill(pid int32, created int64) error {
+	info, err := s.Process(pid)
+	if err != nil {
+		return err
+	}
+	if info.Created != created {
+		return fmt.Errorf("process exited, PID reused")
+	}
+	return syscall.Kill(int(pid), syscall.SIGKILL)
+}
+
+func (s *ProcfsSource) path(pid int32, file string) string {
+	return filepath.Join(s.root, strconv.Itoa(int(pid)), file)
+}
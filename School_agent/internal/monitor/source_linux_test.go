@@ -0,0 +1,32 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcfsCreatedMilliseconds(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "42")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// starttime (поле 22) = 12345 тиков = 123.45 с после загрузки
+	stat := "42 (game (x)) S 1 42 42 0 -1 4194304 0 0 0 0 7 3 0 0 20 0 4 0 12345 1000 50 0\n"
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	src := &ProcfsSource{root: root, bootTime: 1700000000, users: make(map[string]string)}
+	info, err := src.Process(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "game (x)" || info.PPID != 1 {
+		t.Errorf("info: %+v", info)
+	}
+	if want := int64(1700000000*1000 + 123450); info.Created != want {
+		t.Errorf("Created = %d, want %d", info.Created, want)
+	}
+}
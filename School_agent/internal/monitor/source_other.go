@@ -0,0 +1,8 @@
+//go:build !linux
+
+package monitor
+
+// nativeProcessSource — отдельного источника, кроме gopsutil, на этой ОС нет
+func nativeProcessSource() ProcessSource {
+	return nil
+}
@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"fmt"
+	"school_agent/internal/models"
+	"school_agent/internal/rules"
+)
+
+// Глубина, до которой собирается цепочка предков
+const maxAncestry = 8
+
+type procNode struct {
+	key  procKey
+	ppid int32
+	name string
+}
+
+// procTree — дерево процессов на момент одного опроса
+type procTree struct {
+	nodes map[int32]procNode
+}
+
+func newProcTree() *procTree {
+	return &procTree{nodes: make(map[int32]procNode)}
+}
+
+func (t *procTree) add(info ProcessInfo) {
+	t.nodes[info.PID] = procNode{key: info.key(), ppid: info.PPID, name: info.Name}
+}
+
+func (t *procTree) remove(pid int32) {
+	delete(t.nodes, pid)
+}
+
+// alive — ключи всех процессов дерева
+func (t *procTree) alive() map[procKey]bool {
+	keys := make(map[procKey]bool, len(t.nodes))
+	for _, n := range t.nodes {
+		keys[n.key] = true
+	}
+	return keys
+}
+
+// parent возвращает родителя, если он еще жив. Если PID родителя успели
+// переиспользовать, у "родителя" время создания позже, чем у ребенка.
+func (t *procTree) parent(n procNode) (procNode, bool) {
+	if n.ppid == 0 || n.ppid == n.key.pid {
+		return procNode{}, false
+	}
+	p, ok := t.nodes[n.ppid]
+	if !ok {
+		return procNode{}, false
+	}
+	if p.key.created != 0 && n.key.created != 0 && p.key.created > n.key.created {
+		return procNode{}, false
+	}
+	return p, true
+}
+
+// ancestors — предки процесса от родителя к корню
+func (t *procTree) ancestors(pid int32) []procNode {
+	n, ok := t.nodes[pid]
+	if !ok {
+		return nil
+	}
+	var chain []procNode
+	for len(chain) < maxAncestry {
+		p, ok := t.parent(n)
+		if !ok {
+			break
+		}
+		chain = append(chain, p)
+		n = p
+	}
+	return chain
+}
+
+func ancestryNames(chain []procNode) []string {
+	names := make([]string, len(chain))
+	for i, n := range chain {
+		names[i] = n.name
+	}
+	return names
+}
+
+type treeRule struct {
+	models.TreeRule
+	process *rules.Matcher
+	parent  *rules.Matcher
+}
+
+func compileTreeRules(list []models.TreeRule) ([]*treeRule, error) {
+	var out []*treeRule
+	for i, r := range list {
+		if r.Action != models.TreeAlert && r.Action != models.TreeIgnore {
+			return nil, fmt.Errorf("tree rule %d: unknown action %q", i, r.Action)
+		}
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("tree-%d", i)
+		}
+		proc, err := rules.Compile(r.Process)
+		if err != nil {
+			return nil, fmt.Errorf("tree rule %s: %v", r.ID, err)
+		}
+		parent, err := rules.Compile(r.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("tree rule %s: %v", r.ID, err)
+		}
+		out = append(out, &treeRule{TreeRule: r, process: proc, parent: parent})
+	}
+	return out, nil
+}
+
+// matches проверяет процесс и его предков по правилу
+func (r *treeRule) matches(src ProcessSource, info ProcessInfo, chain []procNode, hashes *hashCache) bool {
+	if !matchProcess(src, r.process, info.PID, info.Name, hashes) {
+		return false
+	}
+	for i, a := range chain {
+		if i > 0 && !r.AnyAncestor {
+			break
+		}
+		if matchProcess(src, r.parent, a.key.pid, a.name, hashes) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchProcess проверяет процесс по имени, а при необходимости — по пути и хешу exe.
+// Путь запрашивается у источника, только если без него правила не решают.
+func matchProcess(src ProcessSource, m *rules.Matcher, pid int32, name string, hashes *hashCache) bool {
+	if m.MatchName(name) {
+		return true
+	}
+	if !m.NeedsExe() {
+		return false
+	}
+	exe, err := src.Exe(pid)
+	return err == nil && m.MatchExe(exe, hashes)
+}
@@ -0,0 +1,177 @@
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"school_agent/internal/models"
+	"strings"
+)
+
+const (
+	// Имя строки отчета для адресов, не совпавших ни с одним правилом
+	urlDefaultRule = "default"
+	maxRuleSamples = 5
+)
+
+type urlRule struct {
+	models.URLRule
+	schemes map[string]bool
+	domains []string
+	paths   []string
+}
+
+type urlFilter struct {
+	version string
+	include bool // действие по умолчанию
+	dryRun  bool // только для отчета: решение такого фильтра не применяется
+	rules   []*urlRule
+	report  map[string]*models.URLRuleReport
+}
+
+func compileURLRules(set models.URLRules) (*urlFilter, error) {
+	f := &urlFilter{
+		version: set.Version,
+		dryRun:  set.DryRun,
+		report:  make(map[string]*models.URLRuleReport),
+	}
+
+	switch set.Default {
+	case "", models.URLInclude:
+		f.include = true
+	case models.URLExclude:
+	default:
+		return nil, fmt.Errorf("unknown default action %q", set.Default)
+	}
+
+	ids := make(map[string]bool)
+	for i, r := range set.Rules {
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("rule%d", i+1)
+		}
+		if ids[r.ID] || r.ID == urlDefaultRule {
+			return nil, fmt.Errorf("url rule %s: duplicate id", r.ID)
+		}
+		ids[r.ID] = true
+		if r.Action != models.URLInclude && r.Action != models.URLExclude {
+			return nil, fmt.Errorf("url rule %s: unknown action %q", r.ID, r.Action)
+		}
+
+		c := &urlRule{URLRule: r, schemes: make(map[string]bool)}
+		for _, s := range r.Schemes {
+			c.schemes[strings.ToLower(strings.TrimSuffix(s, "://"))] = true
+		}
+		for _, d := range r.Domains {
+			c.domains = append(c.domains, strings.TrimPrefix(strings.ToLower(d), "www."))
+		}
+		for _, p := range r.Paths {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("url rule %s: bad path pattern %q: %v", r.ID, p, err)
+			}
+			c.paths = append(c.paths, p)
+		}
+		f.rules = append(f.rules, c)
+	}
+	return f, nil
+}
+
+// match возвращает первое совпавшее правило; nil — действует умолчание
+func (f *urlFilter) match(raw string) *urlRule {
+	u, err := url.Parse(raw)
+	if err != nil {
+		u = &url.URL{}
+	}
+	for _, r := range f.rules {
+		if r.matches(raw, u) {
+			return r
+		}
+	}
+	return nil
+}
+
+func (r *urlRule) matches(raw string, u *url.URL) bool {
+	if len(r.schemes) > 0 && !r.schemes[strings.ToLower(u.Scheme)] {
+		return false
+	}
+	if r.MinLength > 0 && len(raw) < r.MinLength {
+		return false
+	}
+	if r.MaxLength > 0 && len(raw) > r.MaxLength {
+		return false
+	}
+	if len(r.domains) > 0 && !matchDomain(r.domains, strings.ToLower(u.Hostname())) {
+		return false
+	}
+	if len(r.paths) > 0 && !matchPath(r.paths, u.Path) {
+		return false
+	}
+	return true
+}
+
+func matchDomain(domains []string, host string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPath(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			if ok, _ := path.Match(pattern, p); ok {
+				return true
+			}
+		} else if strings.HasPrefix(p, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// allow применяет фильтр и учитывает совпадение в отчете. sample — адрес
+// для примеров в отчете (уже без секретов).
+func (f *urlFilter) allow(raw, sample string) bool {
+	id, action, include := urlDefaultRule, models.URLExclude, f.include
+	if include {
+		action = models.URLInclude
+	}
+	if r := f.match(raw); r != nil {
+		id, action, include = r.ID, r.Action, r.Action == models.URLInclude
+	}
+
+	rep, ok := f.report[id]
+	if !ok {
+		rep = &models.URLRuleReport{ID: id, Action: action}
+		f.report[id] = rep
+	}
+	rep.Matched++
+	if len(rep.Samples) < maxRuleSamples {
+		rep.Samples = append(rep.Samples, sample)
+	}
+
+	return include
+}
+
+// Report — совпадения по правилам с момента применения, в порядке правил
+func (f *urlFilter) Report() []models.URLRuleReport {
+	var out []models.URLRuleReport
+	for _, r := range f.rules {
+		if rep, ok := f.report[r.ID]; ok {
+			out = append(out, copyReport(rep))
+		} else {
+			out = append(out, models.URLRuleReport{ID: r.ID, Action: r.Action})
+		}
+	}
+	if rep, ok := f.report[urlDefaultRule]; ok {
+		out = append(out, copyReport(rep))
+	}
+	return out
+}
+
+func copyReport(rep *models.URLRuleReport) models.URLRuleReport {
+	c := *rep
+	c.Samples = append([]string(nil), rep.Samples...)
+	return c
+}
@@ -0,0 +1,64 @@
+package monitor
+
+import (
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"testing"
+)
+
+func TestURLRulesDryRunKeepsActiveFilter(t *testing.T) {
+	bm := NewBrowserMonitor(config.BrowserConfig{}, t.TempDir(), "", nil)
+	if err := bm.SetURLRules(models.URLRules{
+		Version: "active",
+		Rules:   []models.URLRule{{ID: "video", Action: models.URLExclude, Domains: []string{"youtube.com"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bm.SetURLRules(models.URLRules{
+		Version: "trial",
+		Default: models.URLExclude,
+		Rules:   []models.URLRule{{ID: "school", Action: models.URLInclude, Domains: []string{"school.ru"}}},
+		DryRun:  true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Решения принимает действующий фильтр
+	for url, want := range map[string]bool{
+		"https://example.com/":         true,
+		"https://m.youtube.com/watch":  false,
+		"https://school.ru/lesson/1":   true,
+		"https://www.example.org/news": true,
+	} {
+		if got := bm.allowURL(url); got != want {
+			t.Errorf("allowURL(%s) = %v, want %v", url, got, want)
+		}
+	}
+	if v := bm.URLRulesVersion(); v != "active" {
+		t.Errorf("active version = %q", v)
+	}
+
+	// Отчет — по проверяемому набору
+	version, dryRun, report := bm.URLRulesReport()
+	if version != "trial" || !dryRun {
+		t.Fatalf("report of %q, dry run %v", version, dryRun)
+	}
+	matched := map[string]int{}
+	for _, r := range report {
+		matched[r.ID] = r.Matched
+	}
+	if matched["school"] != 1 || matched[urlDefaultRule] != 3 {
+		t.Errorf("dry run report: %+v", report)
+	}
+
+	// Обычный набор завершает проверку
+	if err := bm.SetURLRules(models.URLRules{Version: "next"}); err != nil {
+		t.Fatal(err)
+	}
+	if version, dryRun, _ := bm.URLRulesReport(); version != "next" || dryRun {
+		t.Errorf("after apply: report of %q, dry run %v", version, dryRun)
+	}
+	if !bm.allowURL("https://youtube.com/") {
+		t.Error("new active rules not applied")
+	}
+}
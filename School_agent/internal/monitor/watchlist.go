@@ -0,0 +1,51 @@
+package monitor
+
+import (
+	"school_agent/internal/models"
+	"school_agent/internal/rules"
+	"strings"
+)
+
+// watchlist — скомпилированный models.Watchlist
+type watchlist struct {
+	version  string
+	allUsers bool
+	match    *rules.Matcher
+	tree     []*treeRule
+}
+
+// emptyWatchlist — список до применения правил: ничего не отслеживается
+func emptyWatchlist() *watchlist {
+	wl, _ := compileWatchlist(models.Watchlist{})
+	return wl
+}
+
+func compileWatchlist(wl models.Watchlist) (*watchlist, error) {
+	match, err := rules.Compile(wl.Rules)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := compileTreeRules(wl.TreeRules)
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist{
+		version:  wl.Version,
+		allUsers: wl.AllUserProcesses,
+		match:    match,
+		tree:     tree,
+	}, nil
+}
+
+// isSystemAccount — учетные записи служб, которые не считаются пользовательскими
+func isSystemAccount(username string) bool {
+	if username == "" {
+		return true
+	}
+	switch strings.ToUpper(username) {
+	case `NT AUTHORITY\SYSTEM`, `NT AUTHORITY\LOCAL SERVICE`, `NT AUTHORITY\NETWORK SERVICE`, "ROOT":
+		return true
+	}
+	upper := strings.ToUpper(username)
+	return strings.HasPrefix(upper, `FONT DRIVER HOST\`) || strings.HasPrefix(upper, `WINDOW MANAGER\`)
+}
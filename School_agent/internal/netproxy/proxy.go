@@ -0,0 +1,95 @@
+package netproxy
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"school_agent/internal/config"
+	"strings"
+	"time"
+)
+
+// Func возвращает функцию выбора прокси для net/http и websocket.Dialer.
+// Явный URL из конфига важнее переменных окружения HTTP(S)_PROXY/NO_PROXY.
+func Func(cfg config.ProxyConfig) func(*http.Request) (*url.URL, error) {
+	if cfg.URL == "" {
+		if cfg.FromEnvironment {
+			return http.ProxyFromEnvironment
+		}
+		return nil
+	}
+
+	proxyURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return func(*http.Request) (*url.URL, error) { return nil, err }
+	}
+	if cfg.Username != "" {
+		proxyURL.User = url.UserPassword(cfg.Username, cfg.Password)
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		if bypass(req.URL.Hostname(), requestPort(req.URL), cfg.NoProxy) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}
+}
+
+// HTTPClient создает клиент, который ходит через настроенный прокси
+func HTTPClient(cfg config.ProxyConfig, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = Func(cfg)
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
+
+// requestPort — порт адреса, для ws/wss и http/https без порта — стандартный
+func requestPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch u.Scheme {
+	case "https", "wss":
+		return "443"
+	}
+	return "80"
+}
+
+// bypass проверяет хост по списку исключений в формате NO_PROXY и списка
+// исключений Windows: "*" — все хосты, ".school.local", "*.school.local" или
+// "school.local" — домен с поддоменами, IP-адрес или CIDR-подсеть,
+// "<local>" — имена без точки. Запись "host:port" действует только на этот порт.
+func bypass(host, port string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			if p != port {
+				continue
+			}
+			entry = h
+		}
+
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case entry == "<local>":
+			if ip == nil && !strings.Contains(host, ".") {
+				return true
+			}
+		case ip != nil && strings.Contains(entry, "/"):
+			if _, subnet, err := net.ParseCIDR(entry); err == nil && subnet.Contains(ip) {
+				return true
+			}
+		default:
+			domain := strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package netproxy
+
+import (
+	"net/http"
+	"school_agent/internal/config"
+	"testing"
+)
+
+func TestBypass(t *testing.T) {
+	noProxy := []string{
+		"intranet.school.local",
+		".lan",
+		"*.edu.example",
+		"10.0.0.0/8",
+		"fd00::/8",
+		"192.168.1.20",
+		"<local>",
+		"server.example:8443",
+		"[::1]:443",
+	}
+	tests := []struct {
+		host, port string
+		want       bool
+	}{
+		// Точные хосты и домены с поддоменами
+		{"intranet.school.local", "443", true},
+		{"INTRANET.school.local", "443", true},
+		{"wiki.intranet.school.local", "443", true},
+		{"school.local", "443", false},
+		{"printer.lan", "80", true},
+		{"lan", "80", true},
+		{"portal.edu.example", "443", true},
+		{"edu.example", "443", true},
+		{"notedu.example", "443", false},
+
+		// IP и подсети
+		{"10.1.2.3", "443", true},
+		{"11.1.2.3", "443", false},
+		{"fd12::1", "443", true},
+		{"192.168.1.20", "443", true},
+		{"192.168.1.21", "443", false},
+
+		// <local> — имена без точки, но не IP
+		{"fileserver", "443", true},
+		{"fileserver.example", "443", false},
+
+		// host:port — только этот порт
+		{"server.example", "8443", true},
+		{"server.example", "443", false},
+		{"::1", "443", true},
+		{"::1", "80", false},
+
+		{"agent.example.com", "443", false},
+	}
+	for _, tt := range tests {
+		if got := bypass(tt.host, tt.port, noProxy); got != tt.want {
+			t.Errorf("bypass(%q, %q) = %v, want %v", tt.host, tt.port, got, tt.want)
+		}
+	}
+
+	if !bypass("anything.example", "443", []string{" ", "*"}) {
+		t.Error("* does not bypass every host")
+	}
+	if bypass("anything.example", "443", nil) {
+		t.Error("empty list bypasses the proxy")
+	}
+}
+
+func TestFuncUsesDefaultPorts(t *testing.T) {
+	proxy := Func(config.ProxyConfig{URL: "http://proxy.school.local:3128", NoProxy: []string{"server.example:443", "server.example:80"}})
+	tests := []struct {
+		url      string
+		viaProxy bool
+	}{
+		{"wss://server.example/ws", false},
+		{"https://server.example/agent/messages", false},
+		{"ws://server.example/ws", false},
+		{"https://server.example:8443/agent/messages", true},
+		{"https://other.example/", true},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u, err := proxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (u != nil) != tt.viaProxy {
+			t.Errorf("%s: proxy %v, want via proxy %v", tt.url, u, tt.viaProxy)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package protocol
+
+import (
+	"school_agent/internal/models"
+	"time"
+)
+
+// Версии протокола агент <-> сервер.
+// V1 — исходный формат без поля версии, его понимают все старые серверы.
+const (
+	V1 = 1
+	V2 = 2
+)
+
+// Supported — версии, которые умеет агент, в порядке предпочтения
+var Supported = []int{V2, V1}
+
+// Возможности, о которых агент сообщает при подключении
+const (
+	// CapLogMetadata — записи логов могут содержать поля сверх базового LogEntry
+	CapLogMetadata = "log_metadata"
+	// CapLogChunks — выгрузка логов чанками logs_chunk с подтверждением LOGS_ACK
+	CapLogChunks = "log_chunks"
+)
+
+// Capabilities — все возможности агента
+var Capabilities = []string{CapLogMetadata, CapLogChunks}
+
+// Encoder строит сообщения агента в формате конкретной версии протокола
+type Encoder interface {
+	Version() int
+	Heartbeat(device, user string, ts time.Time) interface{}
+	Logs(device string, entries []models.LogEntry) interface{}
+	// Message оборачивает произвольное сообщение с полем type
+	Message(msgType string, fields map[string]interface{}) interface{}
+}
+
+// For возвращает энкодер для версии со всеми возможностями;
+// неизвестные версии откатываются на V1
+func For(version int) Encoder {
+	return ForCapabilities(version, Capabilities)
+}
+
+// ForCapabilities возвращает энкодер для версии с учетом возможностей,
+// которые подтвердил сервер: без CapLogMetadata записи логов V2 урезаются
+// до базовых полей.
+func ForCapabilities(version int, caps []string) Encoder {
+	switch version {
+	case V2:
+		return v2Encoder{metadata: HasCapability(caps, CapLogMetadata)}
+	default:
+		return v1Encoder{}
+	}
+}
+
+// HasCapability ищет возможность в списке
+func HasCapability(caps []string, capability string) bool {
+	for _, c := range caps {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSupported сообщает, умеет ли агент работать в указанной версии
+func IsSupported(version int) bool {
+	for _, v := range Supported {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate выбирает первую из своих версий (в порядке предпочтения),
+// которую поддерживает другая сторона. Возвращает V1, если пересечения нет.
+func Negotiate(ours, theirs []int) int {
+	for _, v := range ours {
+		for _, tv := range theirs {
+			if v == tv {
+				return v
+			}
+		}
+	}
+	return V1
+}
+
+// Auth — первое сообщение после подключения. Формат auth не зависит от версии:
+// до ответа сервера версия неизвестна, а блок protocol старые серверы игнорируют.
+func Auth(token, device string) interface{} {
+	return map[string]interface{}{
+		"type":   "auth",
+		"token":  token,
+		"device": device,
+		"protocol": map[string]interface{}{
+			"versions":     Supported,
+			"capabilities": Capabilities,
+		},
+	}
+}
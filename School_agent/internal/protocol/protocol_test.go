@@ -0,0 +1,181 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"school_agent/internal/models"
+	"sort"
+	"testing"
+	"time"
+)
+
+// keys сериализует сообщение так же, как транспорт, и возвращает его поля
+func keys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	var m map[string]json.RawMessage
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// entryKeys — поля первой записи в data сообщения logs
+func entryKeys(t *testing.T, v interface{}) []string {
+	t.Helper()
+	var m struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	data, _ := json.Marshal(v)
+	if err := json.Unmarshal(data, &m); err != nil || len(m.Data) != 1 {
+		t.Fatalf("bad logs message %s: %v", data, err)
+	}
+	var out []string
+	for k := range m.Data[0] {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+var fullEntry = models.LogEntry{
+	Username:   "student",
+	DeviceName: "pc-01",
+	Timestamp:  time.Date(2024, 9, 1, 8, 30, 0, 0, time.UTC),
+	LogType:    "browser",
+	Program:    "Chrome",
+	Action:     "Visited: https://example.com",
+	Process:    &models.ProcessDetails{PID: 42},
+	Category:   models.CategoryEducation,
+	Browser:    &models.BrowserVisit{Browser: "Chrome", URL: "https://example.com"},
+}
+
+var baseFields = []string{"action", "device_name", "log_type", "program", "timestamp", "username"}
+
+func TestEncoders(t *testing.T) {
+	ts := time.Date(2024, 9, 1, 8, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		enc       Encoder
+		version   int
+		heartbeat []string
+		logs      []string
+		entry     []string
+		message   []string
+	}{
+		{
+			name:      "v1",
+			enc:       For(V1),
+			version:   V1,
+			heartbeat: []string{"device", "timestamp", "type", "user"},
+			logs:      []string{"data", "type"},
+			entry:     baseFields,
+			message:   []string{"device", "type"},
+		},
+		{
+			name:      "v2",
+			enc:       For(V2),
+			version:   V2,
+			heartbeat: []string{"device", "timestamp", "type", "user", "v"},
+			logs:      []string{"count", "data", "device", "type", "v"},
+			entry:     []string{"action", "browser", "category", "device_name", "log_type", "process", "program", "timestamp", "username"},
+			message:   []string{"device", "type", "v"},
+		},
+		{
+			name:      "v2 without log_metadata",
+			enc:       ForCapabilities(V2, []string{CapLogChunks}),
+			version:   V2,
+			heartbeat: []string{"device", "timestamp", "type", "user", "v"},
+			logs:      []string{"count", "data", "device", "type", "v"},
+			entry:     baseFields,
+			message:   []string{"device", "type", "v"},
+		},
+		{
+			name:      "unknown version falls back to v1",
+			enc:       For(99),
+			version:   V1,
+			heartbeat: []string{"device", "timestamp", "type", "user"},
+			logs:      []string{"data", "type"},
+			entry:     baseFields,
+			message:   []string{"device", "type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if v := tt.enc.Version(); v != tt.version {
+				t.Errorf("Version() = %d, want %d", v, tt.version)
+			}
+			if got := keys(t, tt.enc.Heartbeat("pc-01", "student", ts)); !reflect.DeepEqual(got, tt.heartbeat) {
+				t.Errorf("Heartbeat fields = %v, want %v", got, tt.heartbeat)
+			}
+			logs := tt.enc.Logs("pc-01", []models.LogEntry{fullEntry})
+			if got := keys(t, logs); !reflect.DeepEqual(got, tt.logs) {
+				t.Errorf("Logs fields = %v, want %v", got, tt.logs)
+			}
+			if got := entryKeys(t, logs); !reflect.DeepEqual(got, tt.entry) {
+				t.Errorf("log entry fields = %v, want %v", got, tt.entry)
+			}
+			msg := tt.enc.Message("watchlist_status", map[string]interface{}{"device": "pc-01"})
+			if got := keys(t, msg); !reflect.DeepEqual(got, tt.message) {
+				t.Errorf("Message fields = %v, want %v", got, tt.message)
+			}
+		})
+	}
+}
+
+func TestMessageDoesNotOverrideType(t *testing.T) {
+	for _, enc := range []Encoder{For(V1), For(V2)} {
+		data, _ := json.Marshal(enc.Message("alert", map[string]interface{}{"priority": "high"}))
+		var m map[string]interface{}
+		json.Unmarshal(data, &m)
+		if m["type"] != "alert" || m["priority"] != "high" {
+			t.Errorf("v%d Message = %s", enc.Version(), data)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name         string
+		ours, theirs []int
+		want         int
+	}{
+		{"both support v2", []int{V2, V1}, []int{V1, V2}, V2},
+		{"old server", []int{V2, V1}, []int{V1}, V1},
+		{"old agent sends nothing", []int{V2, V1}, nil, V1},
+		{"no common version", []int{V2}, []int{3}, V1},
+		{"our preference wins", []int{V1, V2}, []int{V2, V1}, V1},
+		{"future version ignored", Supported, []int{3, V2}, V2},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.ours, tt.theirs); got != tt.want {
+			t.Errorf("%s: Negotiate(%v, %v) = %d, want %d", tt.name, tt.ours, tt.theirs, got, tt.want)
+		}
+	}
+}
+
+func TestAuthAdvertisesVersionsAndCapabilities(t *testing.T) {
+	var m struct {
+		Type     string `json:"type"`
+		Protocol struct {
+			Versions     []int    `json:"versions"`
+			Capabilities []string `json:"capabilities"`
+		} `json:"protocol"`
+	}
+	data, _ := json.Marshal(Auth("token", "pc-01"))
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Type != "auth" || !reflect.DeepEqual(m.Protocol.Versions, Supported) || !reflect.DeepEqual(m.Protocol.Capabilities, Capabilities) {
+		t.Errorf("Auth = %s", data)
+	}
+}
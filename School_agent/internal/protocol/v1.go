@@ -0,0 +1,63 @@
+package protocol
+
+import (
+	"school_agent/internal/models"
+	"time"
+)
+
+// v1Encoder — исходный формат сообщений, без поля версии
+type v1Encoder struct{}
+
+// v1LogEntry — ровно те поля LogEntry, которые знают серверы V1
+type v1LogEntry struct {
+	Username   string    `json:"username"`
+	DeviceName string    `json:"device_name"`
+	Timestamp  time.Time `json:"timestamp"`
+	LogType    string    `json:"log_type"`
+	Program    string    `json:"program"`
+	Action     string    `json:"action"`
+}
+
+func (v1Encoder) Version() int {
+	return V1
+}
+
+func (v1Encoder) Heartbeat(device, user string, ts time.Time) interface{} {
+	return map[string]interface{}{
+		"type":      "heartbeat",
+		"device":    device,
+		"user":      user,
+		"timestamp": ts,
+	}
+}
+
+func (v1Encoder) Logs(device string, entries []models.LogEntry) interface{} {
+	return map[string]interface{}{
+		"type": "logs",
+		"data": baseEntries(entries),
+	}
+}
+
+// baseEntries оставляет в записях только поля, известные серверам V1
+func baseEntries(entries []models.LogEntry) []v1LogEntry {
+	data := make([]v1LogEntry, len(entries))
+	for i, e := range entries {
+		data[i] = v1LogEntry{
+			Username:   e.Username,
+			DeviceName: e.DeviceName,
+			Timestamp:  e.Timestamp,
+			LogType:    e.LogType,
+			Program:    e.Program,
+			Action:     e.Action,
+		}
+	}
+	return data
+}
+
+func (v1Encoder) Message(msgType string, fields map[string]interface{}) interface{} {
+	msg := map[string]interface{}{"type": msgType}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	return msg
+}
@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"school_agent/internal/models"
+	"time"
+)
+
+// v2Encoder — каждое сообщение несет поле v и имя устройства.
+// Записи логов передаются со всеми метаданными, если сервер
+// подтвердил CapLogMetadata.
+type v2Encoder struct {
+	metadata bool
+}
+
+func (v2Encoder) Version() int {
+	return V2
+}
+
+func (e v2Encoder) Heartbeat(device, user string, ts time.Time) interface{} {
+	return e.Message("heartbeat", map[string]interface{}{
+		"device":    device,
+		"user":      user,
+		"timestamp": ts,
+	})
+}
+
+func (e v2Encoder) Logs(device string, entries []models.LogEntry) interface{} {
+	var data interface{} = entries
+	if !e.metadata {
+		data = baseEntries(entries)
+	}
+	return e.Message("logs", map[string]interface{}{
+		"device": device,
+		"count":  len(entries),
+		"data":   data,
+	})
+}
+
+func (v2Encoder) Message(msgType string, fields map[string]interface{}) interface{} {
+	msg := map[string]interface{}{"type": msgType, "v": V2}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	return msg
+}
@@ -0,0 +1,186 @@
+// Package rules проверяет программы по правилам models.WatchRule. Один
+// и тот же Matcher используют список отслеживания, политика, правила дерева
+// процессов и дневные лимиты.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"school_agent/internal/models"
+	"strings"
+)
+
+// Identity — хеш и встроенные метаданные exe
+type Identity interface {
+	SHA256(exe string) (string, error)
+	Metadata(exe string) (models.ExeIdentity, error)
+}
+
+// Matcher — скомпилированный список правил. Правила по имени проверяются
+// сразу, по пути, хешу и метаданным — только если известен exe. Правила
+// по имени проверяются и по исходному имени из метаданных: переименованный
+// game.exe все равно подходит под правило "game.exe".
+type Matcher struct {
+	names   map[string]bool
+	globs   []string
+	regexes []*regexp.Regexp
+
+	paths     []string
+	hashes    map[string]bool
+	products  []string
+	originals map[string]bool
+	needsExe  bool
+}
+
+func Compile(list []models.WatchRule) (*Matcher, error) {
+	m := &Matcher{
+		names:     make(map[string]bool),
+		hashes:    make(map[string]bool),
+		originals: make(map[string]bool),
+	}
+
+	for i, r := range list {
+		switch r.Type {
+		case models.RuleName:
+			m.names[strings.ToLower(r.Pattern)] = true
+			m.needsExe = true
+		case models.RuleGlob:
+			pattern := strings.ToLower(r.Pattern)
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: bad glob %q: %v", i, r.Pattern, err)
+			}
+			m.globs = append(m.globs, pattern)
+			m.needsExe = true
+		case models.RuleRegex:
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: bad regex %q: %v", i, r.Pattern, err)
+			}
+			m.regexes = append(m.regexes, re)
+			m.needsExe = true
+		case models.RulePath:
+			m.paths = append(m.paths, NormalizePath(r.Pattern))
+			m.needsExe = true
+		case models.RuleSHA256:
+			m.hashes[strings.ToLower(r.Pattern)] = true
+			m.needsExe = true
+		case models.RuleProduct:
+			pattern := strings.ToLower(r.Pattern)
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: bad product pattern %q: %v", i, r.Pattern, err)
+			}
+			m.products = append(m.products, pattern)
+			m.needsExe = true
+		case models.RuleOriginalName:
+			m.originals[strings.ToLower(r.Pattern)] = true
+			m.needsExe = true
+		default:
+			return nil, fmt.Errorf("rule %d: unknown type %q", i, r.Type)
+		}
+	}
+	return m, nil
+}
+
+// NeedsExe — есть правила, для которых нужен путь к exe
+func (m *Matcher) NeedsExe() bool {
+	return m.needsExe
+}
+
+// Match проверяет программу по имени, а если нужно и exe известен — по exe
+func (m *Matcher) Match(name, exe string, id Identity) bool {
+	return m.MatchName(name) || (m.needsExe && m.MatchExe(exe, id))
+}
+
+// MatchName проверяет правила, которым достаточно имени процесса
+func (m *Matcher) MatchName(name string) bool {
+	lower := strings.ToLower(name)
+	if m.names[lower] {
+		return true
+	}
+	for _, g := range m.globs {
+		if ok, _ := filepath.Match(g, lower); ok {
+			return true
+		}
+	}
+	for _, re := range m.regexes {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchExe проверяет правила по пути, хешу и метаданным исполняемого файла
+func (m *Matcher) MatchExe(exe string, id Identity) bool {
+	if exe == "" {
+		return false
+	}
+	norm := NormalizePath(exe)
+	for _, prefix := range m.paths {
+		if strings.HasPrefix(norm, prefix) {
+			return true
+		}
+	}
+	if id == nil {
+		return false
+	}
+	if len(m.hashes) > 0 {
+		if sum, err := id.SHA256(exe); err == nil && m.hashes[strings.ToLower(sum)] {
+			return true
+		}
+	}
+	if len(m.products) > 0 || len(m.originals) > 0 || m.hasNames() {
+		if meta, err := id.Metadata(exe); err == nil && m.matchMetadata(meta) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Matcher) hasNames() bool {
+	return len(m.names) > 0 || len(m.globs) > 0 || len(m.regexes) > 0
+}
+
+func (m *Matcher) matchMetadata(meta models.ExeIdentity) bool {
+	if meta.OriginalName != "" && (m.originals[strings.ToLower(meta.OriginalName)] || m.MatchName(meta.OriginalName)) {
+		return true
+	}
+	if meta.ProductName == "" {
+		return false
+	}
+	product := strings.ToLower(meta.ProductName)
+	for _, g := range m.products {
+		if ok, _ := filepath.Match(g, product); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func NormalizePath(p string) string {
+	return strings.ToLower(filepath.Clean(strings.ReplaceAll(p, "/", string(filepath.Separator))))
+}
+
+var errNoIdentity = errors.New("exe identity is not collected")
+
+// Known — Identity из метаданных, уже собранных при запуске процесса
+// (ProcessDetails.Identity); nil — метаданные не собирались
+type Known struct {
+	ID *models.ExeIdentity
+}
+
+func (k Known) SHA256(string) (string, error) {
+	if k.ID == nil || k.ID.SHA256 == "" {
+		return "", errNoIdentity
+	}
+	return k.ID.SHA256, nil
+}
+
+func (k Known) Metadata(string) (models.ExeIdentity, error) {
+	if k.ID == nil {
+		return models.ExeIdentity{}, errNoIdentity
+	}
+	return *k.ID, nil
+}
@@ -0,0 +1,100 @@
+package rules
+
+import (
+	"errors"
+	"school_agent/internal/models"
+	"testing"
+)
+
+type fakeIdentity map[string]models.ExeIdentity
+
+func (f fakeIdentity) SHA256(exe string) (string, error) {
+	id, ok := f[exe]
+	if !ok {
+		return "", errors.New("no file")
+	}
+	return id.SHA256, nil
+}
+
+func (f fakeIdentity) Metadata(exe string) (models.ExeIdentity, error) {
+	id, ok := f[exe]
+	if !ok {
+		return models.ExeIdentity{}, errors.New("no file")
+	}
+	return id, nil
+}
+
+func TestMatcher(t *testing.T) {
+	m, err := Compile([]models.WatchRule{
+		{Type: models.RuleName, Pattern: "Game.exe"},
+		{Type: models.RuleGlob, Pattern: "*craft*.exe"},
+		{Type: models.RuleRegex, Pattern: `^steam`},
+		{Type: models.RulePath, Pattern: `C:/Games`},
+		{Type: models.RuleSHA256, Pattern: "ABCDEF"},
+		{Type: models.RuleProduct, Pattern: "Roblox*"},
+		{Type: models.RuleOriginalName, Pattern: "Minecraft.exe"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.NeedsExe() {
+		t.Error("NeedsExe = false with path rules")
+	}
+
+	ids := fakeIdentity{
+		"hashed.exe":  {SHA256: "abcdef"},
+		"roblox.exe":  {ProductName: "Roblox Player"},
+		"renamed.exe": {OriginalName: "minecraft.exe"},
+		"notepad.exe": {ProductName: "Windows", OriginalName: "NOTEPAD.EXE"},
+		// Переименованные копии: правила по имени сверяются с исходным именем
+		"renamed-game.exe":  {OriginalName: "GAME.EXE"},
+		"renamed-steam.exe": {OriginalName: "steam.exe"},
+	}
+	tests := []struct {
+		name, exe string
+		want      bool
+	}{
+		{"game.exe", "", true},
+		{"Minecraft.exe", "", true},
+		{"steamwebhelper.exe", "", true},
+		{"notepad.exe", "", false},
+		{"x.exe", "c:/GAMES/x.exe", true},
+		{"x.exe", "hashed.exe", true},
+		{"x.exe", "roblox.exe", true},
+		{"x.exe", "renamed.exe", true},
+		{"notepad.exe", "notepad.exe", false},
+		{"x.exe", "renamed-game.exe", true},
+		{"x.exe", "renamed-steam.exe", true},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.name, tt.exe, ids); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.name, tt.exe, got, tt.want)
+		}
+	}
+}
+
+func TestMatcherRejectsBadRules(t *testing.T) {
+	for _, r := range []models.WatchRule{
+		{Type: models.RuleGlob, Pattern: "[a"},
+		{Type: models.RuleRegex, Pattern: "("},
+		{Type: models.RuleProduct, Pattern: "[a"},
+		{Type: "size", Pattern: "1"},
+	} {
+		if _, err := Compile([]models.WatchRule{r}); err == nil {
+			t.Errorf("Compile(%+v): no error", r)
+		}
+	}
+}
+
+func TestKnownIdentity(t *testing.T) {
+	m, err := Compile([]models.WatchRule{{Type: models.RuleSHA256, Pattern: "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Match("x.exe", "x.exe", Known{}) {
+		t.Error("matched without collected identity")
+	}
+	if !m.Match("x.exe", "x.exe", Known{ID: &models.ExeIdentity{SHA256: "abc"}}) {
+		t.Error("collected hash not matched")
+	}
+}
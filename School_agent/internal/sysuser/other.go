@@ -0,0 +1,14 @@
+//go:build !windows
+
+package sysuser
+
+import "os"
+
+// GetActiveUser вне Windows возвращает пользователя из окружения —
+// этого достаточно для локальной разработки и интеграционных тестов.
+func GetActiveUser() (string, error) {
+	if user := os.Getenv("SCHOOL_AGENT_USER"); user != "" {
+		return user, nil
+	}
+	return os.Getenv("USER"), nil
+}
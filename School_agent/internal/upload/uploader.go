@@ -0,0 +1,400 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"school_agent/internal/protocol"
+	"school_agent/internal/ws"
+	"sync"
+	"time"
+)
+
+const (
+	ackTimeout = 30 * time.Second
+	stateFile  = "upload_state.json"
+)
+
+// Sender — то, через что уходят чанки (ws.Client)
+type Sender interface {
+	SendJSON(v interface{}) error
+	Encoder() protocol.Encoder
+	HasCapability(capability string) bool
+}
+
+// state — позиция последнего подтвержденного чанка, переживает разрывы и рестарты
+type state struct {
+	File    string `json:"file"`
+	Offset  int64  `json:"offset"`
+	BatchID string `json:"batch_id"`
+	Seq     int    `json:"seq"`
+	// Концы всех чанков пакета: повтор отправляет под тем же batch_id и seq
+	// те же байты, даже если лог с тех пор вырос
+	Ends []int64 `json:"ends,omitempty"`
+}
+
+// errBatchChanged — файл уже не совпадает с границами сохраненного пакета
+var errBatchChanged = errors.New("log file changed since the batch was split")
+
+// chunk — кусок лог-файла в диапазоне байт [start, end)
+type chunk struct {
+	entries []models.LogEntry
+	start   int64
+	end     int64
+	size    int
+}
+
+// Uploader отправляет дневной лог ограниченными по размеру чанками.
+// Если сервер поддерживает log_chunks, каждый чанк ждет подтверждения LOGS_ACK
+// и следующая выгрузка продолжается с последнего подтвержденного места.
+type Uploader struct {
+	cfg       config.UploadConfig
+	hostname  string
+	statePath string
+	sender    Sender
+
+	running sync.Mutex
+	acks    chan models.WSCommand
+	st      state
+}
+
+func New(cfg config.UploadConfig, hostname, stateDir string, sender Sender) *Uploader {
+	u := &Uploader{
+		cfg:       cfg,
+		hostname:  hostname,
+		statePath: filepath.Join(stateDir, stateFile),
+		sender:    sender,
+		acks:      make(chan models.WSCommand, 10),
+	}
+	u.loadState()
+	return u
+}
+
+// Ack передает подтверждение чанка от сервера
+func (u *Uploader) Ack(cmd models.WSCommand) {
+	select {
+	case u.acks <- cmd:
+	default:
+	}
+}
+
+// Upload выгружает текущий лог. Параллельные вызовы пропускаются.
+func (u *Uploader) Upload(currentFile string) {
+	if !u.running.TryLock() {
+		return
+	}
+	defer u.running.Unlock()
+
+	if !u.sender.HasCapability(protocol.CapLogChunks) {
+		u.uploadLegacy(currentFile)
+		return
+	}
+
+	// Сначала дожимаем файл предыдущего дня, если он не был выгружен до конца
+	if u.st.File != "" && u.st.File != currentFile {
+		if err := u.uploadChunked(u.st.File); err != nil {
+			log.Printf("Log upload of %s paused: %v", filepath.Base(u.st.File), err)
+			return
+		}
+		u.st = state{File: currentFile}
+		u.saveState()
+	}
+	if u.st.File == "" {
+		u.st.File = currentFile
+	}
+
+	if err := u.uploadChunked(currentFile); err != nil {
+		log.Printf("Log upload paused: %v", err)
+	}
+}
+
+// uploadChunked отправляет пакеты, пока не дойдет до конца файла: сначала
+// недоотправленный пакет, затем записи, дописанные после него
+func (u *Uploader) uploadChunked(path string) error {
+	for {
+		chunks, err := u.batchChunks(path)
+		if err != nil || len(chunks) == 0 {
+			return err
+		}
+		if err := u.uploadBatch(chunks); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *Uploader) uploadBatch(chunks []chunk) error {
+	sent := 0
+	for _, c := range chunks {
+		last := u.st.Seq == len(u.st.Ends)-1
+		msg, n, err := u.chunkMessage(c, last)
+		if err != nil {
+			return err
+		}
+
+		u.drainAcks()
+		// Без соединения LOGS_ACK не придет: не ждем ackTimeout, продолжим
+		// с этого чанка после переподключения
+		if err := u.sender.SendJSON(msg); errors.Is(err, ws.ErrNotConnected) {
+			return fmt.Errorf("chunk %d of batch %s not sent: %w", u.st.Seq, u.st.BatchID, err)
+		} else if err != nil {
+			return err
+		}
+		if err := u.waitAck(u.st.BatchID, u.st.Seq); err != nil {
+			return err
+		}
+
+		u.st.Offset = c.end
+		u.st.Seq++
+		if last {
+			u.st.BatchID = ""
+			u.st.Seq = 0
+			u.st.Ends = nil
+		}
+		u.saveState()
+
+		sent += len(c.entries)
+		u.throttle(n)
+	}
+
+	log.Printf("Uploaded %d logs to server in %d chunks", sent, len(chunks))
+	return nil
+}
+
+// batchChunks возвращает неподтвержденные чанки текущего пакета или,
+// если пакета нет, делит новые записи на чанки и начинает новый пакет
+func (u *Uploader) batchChunks(path string) ([]chunk, error) {
+	if u.st.BatchID != "" && u.st.Seq < len(u.st.Ends) {
+		chunks, err := u.readChunks(path, u.st.Offset, u.st.Ends[u.st.Seq:])
+		if !errors.Is(err, errBatchChanged) {
+			return chunks, err
+		}
+		log.Printf("Batch %s of %s is abandoned: %v", u.st.BatchID, filepath.Base(path), err)
+		u.st.BatchID, u.st.Seq, u.st.Ends = "", 0, nil
+	}
+
+	chunks, err := u.readChunks(path, u.st.Offset, nil)
+	if err != nil || len(chunks) == 0 {
+		return nil, err
+	}
+	u.st.BatchID = fmt.Sprintf("%s-%s-%d", u.hostname, time.Now().Format("20060102T150405"), u.st.Offset)
+	u.st.Seq = 0
+	u.st.Ends = make([]int64, len(chunks))
+	for i, c := range chunks {
+		u.st.Ends[i] = c.end
+	}
+	// Границы сохраняются до отправки: после рестарта пакет повторится так же
+	u.saveState()
+	return chunks, nil
+}
+
+// uploadLegacy — для серверов без log_chunks: весь файл несколькими сообщениями logs
+func (u *Uploader) uploadLegacy(path string) {
+	chunks, err := u.readChunks(path, 0, nil)
+	if err != nil || len(chunks) == 0 {
+		return
+	}
+
+	sent := 0
+	for _, c := range chunks {
+		if err := u.sender.SendJSON(u.sender.Encoder().Logs(u.hostname, c.entries)); err != nil {
+			log.Printf("Log upload stopped: %v", err)
+			break
+		}
+		sent += len(c.entries)
+		u.throttle(c.size)
+	}
+	log.Printf("Uploaded %d logs to server", sent)
+}
+
+func (u *Uploader) chunkMessage(c chunk, last bool) (interface{}, int, error) {
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fields := map[string]interface{}{
+		"batch_id": u.st.BatchID,
+		"seq":      u.st.Seq,
+		"last":     last,
+		"count":    len(c.entries),
+		"offset":   c.start,
+	}
+
+	if u.cfg.Compression == config.CompressionGzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, 0, err
+		}
+		fields["encoding"] = "gzip"
+		fields["data"] = base64.StdEncoding.EncodeToString(buf.Bytes())
+		return u.sender.Encoder().Message("logs_chunk", fields), buf.Len(), nil
+	}
+
+	fields["encoding"] = "json"
+	fields["data"] = json.RawMessage(data)
+	return u.sender.Encoder().Message("logs_chunk", fields), len(data), nil
+}
+
+// readChunks читает целые строки начиная с offset и режет их на чанки
+// не больше MaxChunkBytes. Недописанная последняя строка пропускается.
+// Если заданы ends, чанки режутся ровно по этим концам и чтение
+// останавливается на последнем из них.
+func (u *Uploader) readChunks(path string, offset int64, ends []int64) ([]chunk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			if len(ends) > 0 {
+				return nil, errBatchChanged
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var chunks []chunk
+	cur := chunk{start: offset, end: offset}
+	pos := offset
+
+	reader := bufio.NewReader(file)
+	for len(ends) == 0 || len(chunks) < len(ends) {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		pos += int64(len(line))
+
+		var entry models.LogEntry
+		if json.Unmarshal(line, &entry) != nil {
+			cur.end = pos
+		} else {
+			if entry.DeviceName == "" {
+				entry.DeviceName = u.hostname
+			}
+			if len(ends) == 0 && cur.size > 0 && cur.size+len(line) > u.cfg.MaxChunkBytes {
+				chunks = append(chunks, cur)
+				cur = chunk{start: cur.end, end: cur.end}
+			}
+			cur.entries = append(cur.entries, entry)
+			cur.size += len(line)
+			cur.end = pos
+		}
+
+		if len(ends) > 0 && pos >= ends[len(chunks)] {
+			if pos != ends[len(chunks)] {
+				return nil, errBatchChanged
+			}
+			chunks = append(chunks, cur)
+			cur = chunk{start: pos, end: pos}
+		}
+	}
+
+	if len(ends) > 0 {
+		if len(chunks) < len(ends) {
+			return nil, errBatchChanged
+		}
+		return chunks, nil
+	}
+	if len(cur.entries) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks, nil
+}
+
+func (u *Uploader) drainAcks() {
+	for {
+		select {
+		case <-u.acks:
+		default:
+			return
+		}
+	}
+}
+
+func (u *Uploader) waitAck(batchID string, seq int) error {
+	timeout := time.After(ackTimeout)
+	for {
+		select {
+		case ack := <-u.acks:
+			if ack.BatchID != batchID || ack.Seq != seq {
+				continue
+			}
+			if ack.Error != "" {
+				return fmt.Errorf("chunk %d rejected: %s", seq, ack.Error)
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("no ack for chunk %d of %s", seq, batchID)
+		}
+	}
+}
+
+// throttle выдерживает паузу, чтобы средняя скорость не превышала лимит
+func (u *Uploader) throttle(bytes int) {
+	if d := u.throttleDelay(bytes, time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (u *Uploader) throttleDelay(bytes int, now time.Time) time.Duration {
+	limit := u.cfg.BandwidthLimitKBps
+	if limit <= 0 || !u.limitActive(now) {
+		return 0
+	}
+	return time.Duration(bytes) * time.Second / time.Duration(limit*1024)
+}
+
+// limitActive — лимит действует только в часы уроков (если они заданы)
+func (u *Uploader) limitActive(now time.Time) bool {
+	if len(u.cfg.LimitHours) == 0 {
+		return true
+	}
+	for _, r := range u.cfg.LimitHours {
+		if r.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *Uploader) loadState() {
+	data, err := os.ReadFile(u.statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Cannot read upload state: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &u.st); err != nil {
+		log.Printf("Upload state is corrupt, starting over: %v", err)
+		u.st = state{}
+	}
+}
+
+func (u *Uploader) saveState() {
+	data, err := json.Marshal(u.st)
+	if err == nil {
+		err = os.WriteFile(u.statePath, data, 0644)
+	}
+	if err != nil {
+		log.Printf("Cannot save upload state: %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package upload
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"school_agent/internal/protocol"
+	"school_agent/internal/ws"
+	"testing"
+	"time"
+)
+
+// offlineSender — сервер поддерживает log_chunks, но соединения сейчас нет
+type offlineSender struct {
+	sends int
+}
+
+func (s *offlineSender) SendJSON(v interface{}) error {
+	s.sends++
+	return ws.ErrNotConnected
+}
+
+func (s *offlineSender) Encoder() protocol.Encoder { return protocol.For(protocol.V2) }
+
+func (s *offlineSender) HasCapability(capability string) bool {
+	return capability == protocol.CapLogChunks
+}
+
+func writeLog(t *testing.T, path string, n int) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for i := 0; i < n; i++ {
+		enc.Encode(models.LogEntry{Username: "pupil", LogType: "process", Program: "game.exe", Action: "Opened"})
+	}
+}
+
+func TestUploadOfflineDoesNotWaitForAck(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "2024-09-02.log")
+	writeLog(t, logPath, 3)
+
+	sender := &offlineSender{}
+	u := New(config.UploadConfig{MaxChunkBytes: 1024, Compression: config.CompressionGzip}, "pc-01", dir, sender)
+
+	start := time.Now()
+	u.Upload(logPath)
+	if elapsed := time.Since(start); elapsed > ackTimeout/10 {
+		t.Fatalf("offline upload took %s", elapsed)
+	}
+	if sender.sends != 1 {
+		t.Errorf("sends = %d, want 1 attempt", sender.sends)
+	}
+	// Позиция не сдвинулась: после переподключения выгрузка начнется с начала
+	if u.st.Offset != 0 || u.st.Seq != 0 {
+		t.Errorf("state moved offline: %+v", u.st)
+	}
+}
+
+// chunkMsg — поля logs_chunk, которые проверяет сервер
+type chunkMsg struct {
+	BatchID string          `json:"batch_id"`
+	Seq     int             `json:"seq"`
+	Last    bool            `json:"last"`
+	Count   int             `json:"count"`
+	Offset  int64           `json:"offset"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// chunkSender принимает чанки; reply решает, каким LOGS_ACK ответить
+type chunkSender struct {
+	u     *Uploader
+	sent  []chunkMsg
+	reply func(m chunkMsg)
+}
+
+func (s *chunkSender) SendJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var m chunkMsg
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	s.sent = append(s.sent, m)
+	if s.reply != nil {
+		s.reply(m)
+	} else {
+		s.ack(m, "")
+	}
+	return nil
+}
+
+func (s *chunkSender) ack(m chunkMsg, rejected string) {
+	s.u.Ack(models.WSCommand{Type: "LOGS_ACK", BatchID: m.BatchID, Seq: m.Seq, Error: rejected})
+}
+
+func (s *chunkSender) Encoder() protocol.Encoder { return protocol.For(protocol.V2) }
+
+func (s *chunkSender) HasCapability(capability string) bool {
+	return capability == protocol.CapLogChunks
+}
+
+// lineSize — длина одной записи writeLog в байтах
+func lineSize(t *testing.T) int {
+	// Opened и Closed одной длины: все записи теста одного размера
+	data, err := json.Marshal(models.LogEntry{Username: "pupil", LogType: "process", Program: "game.exe", Action: "Opened"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(data) + 1
+}
+
+func appendLog(t *testing.T, path string, n int) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for i := 0; i < n; i++ {
+		enc.Encode(models.LogEntry{Username: "pupil", LogType: "process", Program: "game.exe", Action: "Closed"})
+	}
+}
+
+func newChunkUploader(t *testing.T, dir string, perChunk int) (*Uploader, *chunkSender) {
+	sender := &chunkSender{}
+	cfg := config.UploadConfig{MaxChunkBytes: perChunk * lineSize(t), Compression: config.CompressionNone}
+	sender.u = New(cfg, "pc-01", dir, sender)
+	return sender.u, sender
+}
+
+func TestUploadSplitsIntoChunks(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "2024-09-02.log")
+	writeLog(t, logPath, 10)
+
+	u, sender := newChunkUploader(t, dir, 3)
+	u.Upload(logPath)
+
+	if len(sender.sent) != 4 {
+		t.Fatalf("chunks: got %d, want 4", len(sender.sent))
+	}
+	var offset int64
+	for i, m := range sender.sent {
+		wantCount := 3
+		if i == 3 {
+			wantCount = 1
+		}
+		if m.Seq != i || m.Count != wantCount || m.Last != (i == 3) || m.Offset != offset || m.BatchID != sender.sent[0].BatchID {
+			t.Fatalf("chunk %d: %+v", i, m)
+		}
+		offset += int64(m.Count * lineSize(t))
+	}
+
+	info, _ := os.Stat(logPath)
+	if u.st.Offset != info.Size() || u.st.BatchID != "" || u.st.Ends != nil {
+		t.Fatalf("state after upload: %+v, file size %d", u.st, info.Size())
+	}
+
+	// Новых записей нет — повторная выгрузка ничего не шлет
+	u.Upload(logPath)
+	if len(sender.sent) != 4 {
+		t.Fatalf("resent %d chunks", len(sender.sent)-4)
+	}
+}
+
+func TestUploadWaitsForAck(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "2024-09-02.log")
+	writeLog(t, logPath, 4)
+
+	u, sender := newChunkUploader(t, dir, 2)
+	// Подтверждение приходит позже и после чужого LOGS_ACK
+	sender.reply = func(m chunkMsg) {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			sender.ack(chunkMsg{BatchID: "other", Seq: m.Seq}, "")
+			sender.ack(m, "")
+		}()
+	}
+	u.Upload(logPath)
+	if len(sender.sent) != 2 || u.st.BatchID != "" {
+		t.Fatalf("sent %d chunks, state %+v", len(sender.sent), u.st)
+	}
+
+	// Отклоненный чанк не сдвигает позицию
+	appendLog(t, logPath, 4)
+	sender.reply = func(m chunkMsg) {
+		if m.Seq == 1 {
+			sender.ack(m, "disk full")
+		} else {
+			sender.ack(m, "")
+		}
+	}
+	before := u.st.Offset
+	u.Upload(logPath)
+	if u.st.Seq != 1 || u.st.Offset != before+int64(2*lineSize(t)) {
+		t.Fatalf("state after rejected chunk: %+v", u.st)
+	}
+}
+
+func TestUploadResumesBatchAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "2024-09-02.log")
+	writeLog(t, logPath, 5)
+
+	u, sender := newChunkUploader(t, dir, 2)
+	sender.reply = func(m chunkMsg) {
+		if m.Seq == 0 {
+			sender.ack(m, "")
+		} else {
+			// seq 1 не подтвержден, затем агент перезапускается
+			sender.ack(m, "server restarting")
+		}
+	}
+	u.Upload(logPath)
+	if len(sender.sent) != 2 {
+		t.Fatalf("sent %d chunks before restart", len(sender.sent))
+	}
+	unacked := sender.sent[1]
+
+	// Лог вырос, агент перезапустился
+	appendLog(t, logPath, 3)
+	u2, sender2 := newChunkUploader(t, dir, 2)
+	u2.Upload(logPath)
+
+	if len(sender2.sent) == 0 {
+		t.Fatal("nothing sent after restart")
+	}
+	resent := sender2.sent[0]
+	if resent.BatchID != unacked.BatchID || resent.Seq != 1 || resent.Last != unacked.Last ||
+		resent.Offset != unacked.Offset || string(resent.Data) != string(unacked.Data) {
+		t.Fatalf("resent chunk differs:\n got  %+v\n want %+v", resent, unacked)
+	}
+	// Новые записи уходят новым пакетом
+	tail := sender2.sent[len(sender2.sent)-1]
+	if tail.BatchID == unacked.BatchID || !tail.Last {
+		t.Fatalf("new entries not in a new batch: %+v", tail)
+	}
+	info, _ := os.Stat(logPath)
+	if u2.st.Offset != info.Size() {
+		t.Fatalf("offset %d, file size %d", u2.st.Offset, info.Size())
+	}
+}
+
+func TestThrottleDuringLimitHours(t *testing.T) {
+	var lessons models.TimeRange
+	if err := json.Unmarshal([]byte(`"08:00-15:00"`), &lessons); err != nil {
+		t.Fatal(err)
+	}
+	u := &Uploader{cfg: config.UploadConfig{BandwidthLimitKBps: 2, LimitHours: []models.TimeRange{lessons}}}
+
+	day := time.Date(2024, 9, 2, 0, 0, 0, 0, time.Local)
+	if d := u.throttleDelay(2048, day.Add(10*time.Hour)); d != time.Second {
+		t.Errorf("during lessons: got %s, want 1s", d)
+	}
+	if d := u.throttleDelay(2048, day.Add(16*time.Hour)); d != 0 {
+		t.Errorf("after lessons: got %s, want no delay", d)
+	}
+
+	u.cfg.LimitHours = nil
+	if d := u.throttleDelay(2048, day.Add(16*time.Hour)); d != time.Second {
+		t.Errorf("without limit hours: got %s, want 1s", d)
+	}
+	u.cfg.BandwidthLimitKBps = 0
+	if d := u.throttleDelay(2048, day.Add(10*time.Hour)); d != 0 {
+		t.Errorf("without limit: got %s", d)
+	}
+}
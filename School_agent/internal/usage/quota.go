@@ -0,0 +1,356 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"school_agent/internal/models"
+	"school_agent/internal/rules"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	quotaStateFile = "quota_usage.json"
+	// Пороги предупреждений, % от лимита
+	quotaWarnLevel  = 80
+	quotaLimitLevel = 100
+	// Если проверки долго не было (агент стоял), время за перерыв не начисляется
+	maxQuotaStep = time.Minute
+	defaultGrace = 60 * time.Second
+)
+
+type quotaRule struct {
+	models.AppQuota
+	match *rules.Matcher
+	users map[string]bool
+}
+
+func (r *quotaRule) appliesTo(user string) bool {
+	return len(r.users) == 0 || r.users[strings.ToLower(user)]
+}
+
+// matches проверяет приложение по Match. Правилам sha256, product и
+// original_name нужны метаданные exe, собранные при запуске (process.identity).
+func (r *quotaRule) matches(app OpenApp) bool {
+	return r.match.Match(app.App, app.Exe, rules.Known{ID: app.Identity})
+}
+
+func (r *quotaRule) limit() time.Duration {
+	return time.Duration(r.LimitMin) * time.Minute
+}
+
+func (r *quotaRule) grace() time.Duration {
+	if r.GraceSec > 0 {
+		return time.Duration(r.GraceSec) * time.Second
+	}
+	return defaultGrace
+}
+
+type quotaUsage struct {
+	UsedSec float64 `json:"used_sec"`
+	// Последний показанный порог предупреждения
+	Warned    int       `json:"warned"`
+	KillAfter time.Time `json:"kill_after,omitempty"`
+}
+
+type quotaState struct {
+	PeriodStart time.Time `json:"period_start"`
+	Checked     time.Time `json:"checked"`
+	// пользователь -> лимит
+	Used map[string]map[string]*quotaUsage `json:"used"`
+}
+
+// QuotaEnforcer следит за дневными лимитами времени по открытым сессиям
+// Tracker: предупреждает через оболочку на 80% и 100% лимита и при
+// необходимости завершает приложения. Расход хранится в каталоге состояния.
+type QuotaEnforcer struct {
+	path      string
+	tracker   *Tracker
+	notify    func(user, program, message string, countdown int) bool
+	terminate func(user string, apps []string) int
+	report    func(program, action string)
+	// Категория приложения для лимитов с Category
+	categoryOf func(app string) string
+
+	mu      sync.Mutex
+	version string
+	resetAt models.Clock
+	rules   []*quotaRule
+	st      quotaState
+}
+
+func NewQuotaEnforcer(stateDir string, tracker *Tracker, notify func(user, program, message string, countdown int) bool, terminate func(user string, apps []string) int, report func(program, action string)) *QuotaEnforcer {
+	q := &QuotaEnforcer{
+		path:      filepath.Join(stateDir, quotaStateFile),
+		tracker:   tracker,
+		notify:    notify,
+		terminate: terminate,
+		report:    report,
+		st:        quotaState{Used: make(map[string]map[string]*quotaUsage)},
+	}
+	q.load()
+	return q
+}
+
+// Apply заменяет лимиты. Накопленный расход сохраняется для лимитов с тем же ID.
+func (q *QuotaEnforcer) Apply(set models.Quotas) error {
+	var list []*quotaRule
+	for i, r := range set.Rules {
+		if r.ID == "" {
+			return fmt.Errorf("quota %d: empty id", i)
+		}
+		if r.LimitMin <= 0 {
+			return fmt.Errorf("quota %s: limit_min must be positive", r.ID)
+		}
+		if len(r.Match) == 0 && r.Category == "" {
+			return fmt.Errorf("quota %s: neither match nor category set", r.ID)
+		}
+		match, err := rules.Compile(r.Match)
+		if err != nil {
+			return fmt.Errorf("quota %s: %v", r.ID, err)
+		}
+		c := &quotaRule{AppQuota: r, match: match, users: make(map[string]bool)}
+		for _, u := range r.Users {
+			c.users[strings.ToLower(u)] = true
+		}
+		list = append(list, c)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.version = set.Version
+	q.resetAt = set.ResetAt
+	q.rules = list
+
+	log.Printf("Quotas %q applied: %d rules, reset at %s", set.Version, len(list), set.ResetAt)
+	return nil
+}
+
+// SetCategorizer подключает справочник категорий для лимитов по категории
+func (q *QuotaEnforcer) SetCategorizer(categoryOf func(app string) string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.categoryOf = categoryOf
+}
+
+func (q *QuotaEnforcer) Version() string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.version
+}
+
+func (q *QuotaEnforcer) Start() {
+	go func() {
+		ticker := time.NewTicker(10 * time.Second)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			q.Check(now)
+		}
+	}()
+}
+
+// Виды quotaNotice
+const (
+	noticeWarn = iota
+	noticeLimit
+	noticeTerminate
+)
+
+// quotaNotice — решение Check, которое выполняется после снятия q.mu:
+// уведомление оболочки и завершение процессов могут идти долго
+type quotaNotice struct {
+	kind      int
+	rule      *quotaRule
+	user      string
+	running   []string
+	level     int
+	message   string
+	countdown int
+}
+
+// Check начисляет время с прошлой проверки и применяет лимиты
+func (q *QuotaEnforcer) Check(now time.Time) {
+	open := q.tracker.OpenApps()
+
+	q.mu.Lock()
+	q.rollPeriod(now)
+	step := now.Sub(q.st.Checked)
+	if step < 0 || step > maxQuotaStep {
+		step = 0
+	}
+	q.st.Checked = now
+
+	var notices []quotaNotice
+	for user, apps := range open {
+		for _, r := range q.rules {
+			if !r.appliesTo(user) {
+				continue
+			}
+			var running []string
+			for _, app := range apps {
+				if r.matches(app) || (r.Category != "" && q.categoryOf != nil && q.categoryOf(app.App) == r.Category) {
+					running = append(running, app.App)
+				}
+			}
+			if len(running) == 0 {
+				continue
+			}
+
+			u := q.usage(user, r.ID)
+			u.UsedSec += step.Seconds()
+			notices = append(notices, q.enforce(r, u, user, running, now)...)
+		}
+	}
+	q.save()
+	q.mu.Unlock()
+
+	for _, n := range notices {
+		q.apply(n)
+	}
+}
+
+// enforce обновляет пороги предупреждений и решает, что сделать с приложениями
+func (q *QuotaEnforcer) enforce(r *quotaRule, u *quotaUsage, user string, running []string, now time.Time) []quotaNotice {
+	var notices []quotaNotice
+	used := time.Duration(u.UsedSec * float64(time.Second))
+	level := int(100 * used / r.limit())
+
+	switch {
+	case level >= quotaLimitLevel && u.Warned < quotaLimitLevel:
+		u.Warned = quotaLimitLevel
+		message := r.Message
+		if message == "" {
+			message = fmt.Sprintf("Дневной лимит «%s» (%d мин) исчерпан", r.ID, r.LimitMin)
+		}
+		countdown := 0
+		if r.Terminate {
+			u.KillAfter = now.Add(r.grace())
+			countdown = int(r.grace().Seconds())
+			message += ", программа будет закрыта"
+		}
+		notices = append(notices, quotaNotice{kind: noticeLimit, rule: r, user: user, message: message, countdown: countdown})
+
+	case level >= quotaWarnLevel && u.Warned < quotaWarnLevel:
+		u.Warned = quotaWarnLevel
+		left := int(math.Ceil((r.limit() - used).Minutes()))
+		message := fmt.Sprintf("До конца дневного лимита «%s» осталось %d мин", r.ID, left)
+		notices = append(notices, quotaNotice{kind: noticeWarn, rule: r, user: user, level: level, message: message})
+	}
+
+	if r.Terminate && !u.KillAfter.IsZero() && !now.Before(u.KillAfter) {
+		notices = append(notices, quotaNotice{kind: noticeTerminate, rule: r, user: user, running: running})
+	}
+	return notices
+}
+
+func (q *QuotaEnforcer) apply(n quotaNotice) {
+	r := n.rule
+	switch n.kind {
+	case noticeLimit:
+		q.reportf(r, "limit reached for %s (%s)", n.user, q.deliver(n.user, r, n.message, n.countdown))
+
+	case noticeWarn:
+		q.reportf(r, "%d%% used by %s (%s)", n.level, n.user, q.deliver(n.user, r, n.message, 0))
+
+	case noticeTerminate:
+		if q.terminate == nil {
+			return
+		}
+		if killed := q.terminate(n.user, n.running); killed > 0 {
+			message := fmt.Sprintf("Дневной лимит «%s» исчерпан, программа закрыта", r.ID)
+			q.reportf(r, "terminated %d process(es) of %s for %s (%s)", killed, strings.Join(n.running, ", "), n.user, q.deliver(n.user, r, message, 0))
+		}
+	}
+}
+
+func (q *QuotaEnforcer) deliver(user string, r *quotaRule, message string, countdown int) string {
+	if q.notify != nil && q.notify(user, r.ID, message, countdown) {
+		return "shell notified"
+	}
+	return "shell not connected"
+}
+
+// Status возвращает остатки лимитов пользователя
+func (q *QuotaEnforcer) Status(user string, now time.Time) []models.QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.rollPeriod(now)
+	var out []models.QuotaStatus
+	for _, r := range q.rules {
+		if !r.appliesTo(user) {
+			continue
+		}
+		s := models.QuotaStatus{ID: r.ID, LimitSec: r.limit().Seconds()}
+		if u, ok := q.st.Used[strings.ToLower(user)][r.ID]; ok {
+			s.UsedSec = u.UsedSec
+		}
+		s.RemainingSec = math.Max(0, s.LimitSec-s.UsedSec)
+		s.Exceeded = s.UsedSec >= s.LimitSec
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// rollPeriod обнуляет расход, если с прошлой проверки прошло время сброса
+func (q *QuotaEnforcer) rollPeriod(now time.Time) {
+	start := q.resetAt.Last(now)
+	if q.st.PeriodStart.Equal(start) {
+		return
+	}
+	if !q.st.PeriodStart.IsZero() {
+		log.Printf("Quotas reset at %s", start.Format(time.RFC3339))
+	}
+	q.st.PeriodStart = start
+	q.st.Used = make(map[string]map[string]*quotaUsage)
+}
+
+func (q *QuotaEnforcer) usage(user, id string) *quotaUsage {
+	user = strings.ToLower(user)
+	byRule, ok := q.st.Used[user]
+	if !ok {
+		byRule = make(map[string]*quotaUsage)
+		q.st.Used[user] = byRule
+	}
+	u, ok := byRule[id]
+	if !ok {
+		u = &quotaUsage{}
+		byRule[id] = u
+	}
+	return u
+}
+
+func (q *QuotaEnforcer) reportf(r *quotaRule, format string, args ...interface{}) {
+	action := fmt.Sprintf("Quota %s: %s", r.ID, fmt.Sprintf(format, args...))
+	log.Print(action)
+	if q.report != nil {
+		q.report(r.ID, action)
+	}
+}
+
+func (q *QuotaEnforcer) load() {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		return
+	}
+	var st quotaState
+	if json.Unmarshal(data, &st) != nil || st.Used == nil {
+		return
+	}
+	q.st = st
+}
+
+func (q *QuotaEnforcer) save() {
+	data, err := json.Marshal(q.st)
+	if err != nil {
+		return
+	}
+	os.WriteFile(q.path, data, 0644)
+}
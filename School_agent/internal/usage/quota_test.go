@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"school_agent/internal/models"
+	"testing"
+	"time"
+)
+
+// Оболочка и завершение процессов вызываются без q.mu: обработчики
+// обращаются к QuotaEnforcer и не должны его блокировать
+func TestQuotaNotifiesOutsideLock(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2024, 9, 2, 10, 0, 0, 0, time.Local)
+
+	tracker := New(dir)
+	tracker.ProcessOpened("pupil", "game.exe", &models.ProcessDetails{PID: 1}, t0)
+
+	var q *QuotaEnforcer
+	var warnings []int
+	var warned []string
+	var killed []string
+	q = NewQuotaEnforcer(dir, tracker,
+		func(user, program, message string, countdown int) bool {
+			q.Status("pupil", t0)
+			warnings = append(warnings, countdown)
+			warned = append(warned, user)
+			return true
+		},
+		func(user string, apps []string) int {
+			q.Version()
+			killed = append(killed, apps...)
+			return len(apps)
+		}, nil)
+	if err := q.Apply(models.Quotas{Rules: []models.AppQuota{{
+		ID:        "games",
+		Match:     []models.WatchRule{{Type: models.RuleName, Pattern: "game.exe"}},
+		LimitMin:  1,
+		Terminate: true,
+		GraceSec:  10,
+	}}}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for _, sec := range []int{0, 30, 50, 60, 71} {
+			q.Check(t0.Add(time.Duration(sec) * time.Second))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Check deadlocked calling notify or terminate")
+	}
+
+	// 80%, 100% с отсчетом, сообщение о закрытии
+	if len(warnings) != 3 || warnings[0] != 0 || warnings[1] != 10 || warnings[2] != 0 {
+		t.Errorf("warnings: got %v, want [0 10 0]", warnings)
+	}
+	for _, user := range warned {
+		if user != "pupil" {
+			t.Errorf("warning sent to %q, want pupil", user)
+		}
+	}
+	if len(killed) != 1 || killed[0] != "game.exe" {
+		t.Errorf("terminated: got %v, want [game.exe]", killed)
+	}
+}
+
+func TestQuotaMatchesExeRules(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2024, 9, 2, 10, 0, 0, 0, time.Local)
+
+	tracker := New(dir)
+	tracker.ProcessOpened("pupil", "renamed.exe", &models.ProcessDetails{
+		PID:      7,
+		Exe:      `C:\Users\pupil\Downloads\renamed.exe`,
+		Identity: &models.ExeIdentity{SHA256: "abc", ProductName: "Roblox"},
+	}, t0)
+
+	q := NewQuotaEnforcer(dir, tracker, nil, nil, nil)
+	if err := q.Apply(models.Quotas{Rules: []models.AppQuota{
+		{ID: "by-hash", LimitMin: 10, Match: []models.WatchRule{{Type: models.RuleSHA256, Pattern: "ABC"}}},
+		{ID: "by-product", LimitMin: 10, Match: []models.WatchRule{{Type: models.RuleProduct, Pattern: "roblox*"}}},
+		{ID: "by-path", LimitMin: 10, Match: []models.WatchRule{{Type: models.RulePath, Pattern: `C:\Users\pupil\Downloads`}}},
+		{ID: "other", LimitMin: 10, Match: []models.WatchRule{{Type: models.RulePath, Pattern: `C:\Games`}}},
+	}}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	q.Check(t0)
+	q.Check(t0.Add(time.Minute))
+	for _, s := range q.Status("pupil", t0.Add(time.Minute)) {
+		want := 60.0
+		if s.ID == "other" {
+			want = 0
+		}
+		if s.UsedSec != want {
+			t.Errorf("%s: used %.0fs, want %.0fs", s.ID, s.UsedSec, want)
+		}
+	}
+}
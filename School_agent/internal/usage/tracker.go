@@ -0,0 +1,322 @@
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"school_agent/internal/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	stateFile  = "usage.json"
+	dateFormat = "2006-01-02"
+	keepDays   = 7
+)
+
+// openApp — непрерывная сессия приложения: пока открыт хотя бы один его процесс.
+// Несколько процессов одного приложения (chrome.exe) считаются одной сессией.
+type openApp struct {
+	User      string          `json:"user"`
+	App       string          `json:"app"`
+	Instances map[string]bool `json:"instances"`
+	Since     time.Time       `json:"since"`
+	Accounted time.Time       `json:"accounted"`
+	// exe и его метаданные первого процесса сессии — для лимитов по пути и хешу
+	Exe      string              `json:"exe,omitempty"`
+	Identity *models.ExeIdentity `json:"identity,omitempty"`
+}
+
+// OpenApp — запущенное приложение пользователя
+type OpenApp struct {
+	App      string
+	Exe      string
+	Identity *models.ExeIdentity
+}
+
+type state struct {
+	// дата -> пользователь -> приложение
+	Days map[string]map[string]map[string]*models.AppUsage `json:"days"`
+	Open map[string]*openApp                               `json:"open"`
+	Day  string                                            `json:"day"`
+}
+
+// Tracker считает время работы приложений по событиям Opened/Closed
+// и хранит дневные итоги в каталоге состояния, чтобы пережить перезапуск.
+type Tracker struct {
+	path string
+	now  func() time.Time
+
+	mu sync.Mutex
+	st state
+	// Сессии, открытые до перезапуска агента. Если монитор снова сообщит
+	// о тех же процессах, сессия продолжается без нового запуска.
+	resumable map[string]*openApp
+}
+
+func New(stateDir string) *Tracker {
+	return newTracker(stateDir, time.Now)
+}
+
+func newTracker(stateDir string, now func() time.Time) *Tracker {
+	t := &Tracker{
+		path: filepath.Join(stateDir, stateFile),
+		now:  now,
+		st: state{
+			Days: make(map[string]map[string]map[string]*models.AppUsage),
+			Open: make(map[string]*openApp),
+			Day:  now().Format(dateFormat),
+		},
+		resumable: make(map[string]*openApp),
+	}
+	t.load()
+	return t
+}
+
+func sessionKey(user, app string) string {
+	return user + "|" + app
+}
+
+// InstanceKey — идентификатор экземпляра процесса (PID + время создания)
+func InstanceKey(d *models.ProcessDetails) string {
+	return fmt.Sprintf("%d-%d", d.PID, d.StartTime.UnixMilli())
+}
+
+func (t *Tracker) ProcessOpened(user, app string, details *models.ProcessDetails, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	instance := InstanceKey(details)
+	key := sessionKey(user, app)
+	if o, ok := t.st.Open[key]; ok {
+		o.Instances[instance] = true
+		return
+	}
+
+	if r, ok := t.resumable[key]; ok && r.Instances[instance] {
+		delete(t.resumable, key)
+		r.Instances = map[string]bool{instance: true}
+		r.Accounted = now
+		r.Exe, r.Identity = details.Exe, details.Identity
+		t.st.Open[key] = r
+		return
+	}
+
+	t.st.Open[key] = &openApp{
+		User:      user,
+		App:       app,
+		Instances: map[string]bool{instance: true},
+		Since:     now,
+		Accounted: now,
+		Exe:       details.Exe,
+		Identity:  details.Identity,
+	}
+	t.usage(now.Format(dateFormat), user, app).Launches++
+}
+
+func (t *Tracker) ProcessClosed(user, app, instance string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := sessionKey(user, app)
+	o, ok := t.st.Open[key]
+	if !ok {
+		return
+	}
+	delete(o.Instances, instance)
+	if len(o.Instances) > 0 {
+		return
+	}
+
+	t.endSession(key, o, now)
+}
+
+// AddFocus начисляет время фокуса окна приложения, закончившегося в end
+func (t *Tracker) AddFocus(user, app string, seconds float64, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start := end.Add(-time.Duration(seconds * float64(time.Second)))
+	for start.Before(end) {
+		y, m, d := start.Date()
+		next := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+		if end.Before(next) {
+			next = end
+		}
+		t.usage(start.Format(dateFormat), user, app).FocusSec += next.Sub(start).Seconds()
+		start = next
+	}
+}
+
+// EndUser закрывает все сессии пользователя (выход из системы)
+func (t *Tracker) EndUser(user string, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, o := range t.st.Open {
+		if o.User == user {
+			t.endSession(key, o, now)
+		}
+	}
+}
+
+func (t *Tracker) endSession(key string, o *openApp, now time.Time) {
+	t.accrue(o, now)
+	delete(t.st.Open, key)
+
+	u := t.usage(now.Format(dateFormat), o.User, o.App)
+	if length := now.Sub(o.Since).Seconds(); length > u.LongestSec {
+		u.LongestSec = length
+	}
+}
+
+// Tick начисляет время открытым сессиям и сохраняет состояние.
+// Если наступили новые сутки, возвращает сводки за завершившийся день.
+func (t *Tracker) Tick(now time.Time) []models.UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range t.st.Open {
+		t.accrue(o, now)
+	}
+
+	var finished []models.UsageSummary
+	today := now.Format(dateFormat)
+	if t.st.Day != today {
+		for user := range t.st.Days[t.st.Day] {
+			finished = append(finished, t.summary(t.st.Day, user))
+		}
+		t.st.Day = today
+		t.prune(now)
+	}
+
+	t.save()
+	return finished
+}
+
+// Day возвращает учетный день, который закроет следующий Tick после полуночи
+func (t *Tracker) Day() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.st.Day
+}
+
+// Summary возвращает сводку пользователя за дату (с учетом открытых сессий)
+func (t *Tracker) Summary(date, user string, now time.Time) models.UsageSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range t.st.Open {
+		t.accrue(o, now)
+	}
+	return t.summary(date, user)
+}
+
+// OpenApps возвращает запущенные сейчас приложения по пользователям
+func (t *Tracker) OpenApps() map[string][]OpenApp {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	apps := make(map[string][]OpenApp)
+	for _, o := range t.st.Open {
+		apps[o.User] = append(apps[o.User], OpenApp{App: o.App, Exe: o.Exe, Identity: o.Identity})
+	}
+	return apps
+}
+
+// Users возвращает пользователей, у которых есть данные за дату
+func (t *Tracker) Users(date string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var users []string
+	for user := range t.st.Days[date] {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
+
+func (t *Tracker) summary(date, user string) models.UsageSummary {
+	s := models.UsageSummary{Date: date, User: user}
+	for _, u := range t.st.Days[date][user] {
+		s.Apps = append(s.Apps, *u)
+	}
+	sort.Slice(s.Apps, func(i, j int) bool { return s.Apps[i].TotalSec > s.Apps[j].TotalSec })
+	return s
+}
+
+// accrue начисляет время с прошлого учета, разбивая его по суткам
+func (t *Tracker) accrue(o *openApp, now time.Time) {
+	for o.Accounted.Before(now) {
+		y, m, d := o.Accounted.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, o.Accounted.Location())
+		end := now
+		if midnight.Before(end) {
+			end = midnight
+		}
+		t.usage(o.Accounted.Format(dateFormat), o.User, o.App).TotalSec += end.Sub(o.Accounted).Seconds()
+		o.Accounted = end
+	}
+}
+
+func (t *Tracker) usage(date, user, app string) *models.AppUsage {
+	users, ok := t.st.Days[date]
+	if !ok {
+		users = make(map[string]map[string]*models.AppUsage)
+		t.st.Days[date] = users
+	}
+	apps, ok := users[user]
+	if !ok {
+		apps = make(map[string]*models.AppUsage)
+		users[user] = apps
+	}
+	u, ok := apps[app]
+	if !ok {
+		u = &models.AppUsage{App: app}
+		apps[app] = u
+	}
+	return u
+}
+
+func (t *Tracker) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -keepDays).Format(dateFormat)
+	for date := range t.st.Days {
+		if date < cutoff {
+			delete(t.st.Days, date)
+		}
+	}
+}
+
+func (t *Tracker) load() {
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return
+	}
+	var st state
+	if json.Unmarshal(data, &st) != nil || st.Days == nil {
+		return
+	}
+
+	// Время, пока агент не работал, не начисляется: открытые сессии
+	// ждут, что монитор снова увидит их процессы
+	for key, o := range st.Open {
+		t.resumable[key] = o
+	}
+	st.Open = make(map[string]*openApp)
+	if st.Day == "" {
+		st.Day = t.now().Format(dateFormat)
+	}
+	t.st = st
+}
+
+func (t *Tracker) save() {
+	data, err := json.Marshal(t.st)
+	if err != nil {
+		return
+	}
+	os.WriteFile(t.path, data, 0644)
+}
@@ -0,0 +1,95 @@
+package usage
+
+import (
+	"school_agent/internal/models"
+	"testing"
+	"time"
+)
+
+// testClock — часы трекера, которые двигает тест
+type testClock struct{ t time.Time }
+
+func (c *testClock) Now() time.Time { return c.t }
+
+func (c *testClock) Advance(d time.Duration) time.Time {
+	c.t = c.t.Add(d)
+	return c.t
+}
+
+func appUsage(s models.UsageSummary, app string) models.AppUsage {
+	for _, u := range s.Apps {
+		if u.App == app {
+			return u
+		}
+	}
+	return models.AppUsage{}
+}
+
+// Несколько процессов приложения — одна сессия: один запуск,
+// время начисляется один раз и сессия длится до закрытия последнего
+func TestTrackerAccumulatesSession(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 9, 2, 10, 0, 0, 0, time.Local)}
+	tr := newTracker(t.TempDir(), clock.Now)
+
+	first := &models.ProcessDetails{PID: 1, StartTime: clock.Now()}
+	tr.ProcessOpened("pupil", "chrome.exe", first, clock.Now())
+	second := &models.ProcessDetails{PID: 2, StartTime: clock.Advance(10 * time.Minute)}
+	tr.ProcessOpened("pupil", "chrome.exe", second, clock.Now())
+
+	tr.Tick(clock.Advance(10 * time.Minute))
+	tr.ProcessClosed("pupil", "chrome.exe", InstanceKey(first), clock.Advance(10*time.Minute))
+	tr.ProcessClosed("pupil", "chrome.exe", InstanceKey(second), clock.Advance(30*time.Minute))
+	tr.AddFocus("pupil", "chrome.exe", 120, clock.Now())
+
+	u := appUsage(tr.Summary("2024-09-02", "pupil", clock.Advance(time.Hour)), "chrome.exe")
+	if u.Launches != 1 || u.TotalSec != 3600 || u.LongestSec != 3600 || u.FocusSec != 120 {
+		t.Fatalf("usage: %+v", u)
+	}
+}
+
+// Сессия через полночь делится по суткам, а первый Tick нового дня
+// возвращает итоги за прошедший
+func TestTrackerRollover(t *testing.T) {
+	clock := &testClock{t: time.Date(2024, 9, 2, 23, 0, 0, 0, time.Local)}
+	tr := newTracker(t.TempDir(), clock.Now)
+
+	tr.ProcessOpened("pupil", "game.exe", &models.ProcessDetails{PID: 1, StartTime: clock.Now()}, clock.Now())
+	if finished := tr.Tick(clock.Advance(30 * time.Minute)); len(finished) != 0 {
+		t.Fatalf("summaries before midnight: %+v", finished)
+	}
+
+	finished := tr.Tick(clock.Advance(time.Hour))
+	if len(finished) != 1 || finished[0].Date != "2024-09-02" || finished[0].User != "pupil" {
+		t.Fatalf("finished: %+v", finished)
+	}
+	if u := appUsage(finished[0], "game.exe"); u.TotalSec != 3600 || u.Launches != 1 {
+		t.Fatalf("previous day: %+v", u)
+	}
+	if u := appUsage(tr.Summary("2024-09-03", "pupil", clock.Now()), "game.exe"); u.TotalSec != 1800 {
+		t.Fatalf("new day: %+v", u)
+	}
+
+	if finished := tr.Tick(clock.Advance(time.Minute)); len(finished) != 0 {
+		t.Fatalf("day closed twice: %+v", finished)
+	}
+}
+
+// День, на котором агент остановился, закрывается после перезапуска
+func TestTrackerRolloverAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	clock := &testClock{t: time.Date(2024, 9, 2, 15, 0, 0, 0, time.Local)}
+	tr := newTracker(dir, clock.Now)
+	details := &models.ProcessDetails{PID: 1, StartTime: clock.Now()}
+	tr.ProcessOpened("pupil", "game.exe", details, clock.Now())
+	tr.Tick(clock.Advance(time.Hour))
+
+	clock.Advance(24 * time.Hour)
+	tr = newTracker(dir, clock.Now)
+	finished := tr.Tick(clock.Now())
+	if len(finished) != 1 || finished[0].Date != "2024-09-02" {
+		t.Fatalf("finished: %+v", finished)
+	}
+	if u := appUsage(finished[0], "game.exe"); u.TotalSec != 3600 {
+		t.Fatalf("previous day: %+v", u)
+	}
+}
@@ -0,0 +1,71 @@
+// Package userhelper — помощник агента в сессии пользователя. Служба
+// работает в сессии 0 и не видит рабочий стол ученика, поэтому запускает
+// свою копию с аргументом Arg в консольной сессии; копия сообщает активное
+// окно по IPC.
+package userhelper
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"school_agent/internal/ipc"
+	"school_agent/internal/models"
+	"school_agent/internal/monitor"
+	"strconv"
+	"time"
+)
+
+const (
+	// Arg — первый аргумент командной строки режима помощника
+	Arg = "foreground-helper"
+	// CmdForeground — отчет помощника об активном окне
+	CmdForeground = "foreground"
+	// Без связи со службой дольше этого помощник завершается
+	maxOffline = time.Minute
+)
+
+// Run — цикл помощника. args: интервал опроса в секундах.
+func Run(args []string) {
+	interval := time.Second
+	if len(args) > 0 {
+		if sec, err := strconv.Atoi(args[0]); err == nil && sec > 0 {
+			interval = time.Duration(sec) * time.Second
+		}
+	}
+
+	source := monitor.NewForegroundSource()
+	lastConnected := time.Now()
+	for time.Since(lastConnected) < maxOffline {
+		conn, err := ipc.Dial()
+		if err != nil {
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		report(conn, source, interval)
+		conn.Close()
+		lastConnected = time.Now()
+	}
+	log.Printf("Foreground helper: agent unreachable for %s, exiting", maxOffline)
+}
+
+// report шлет отчеты, пока служба отвечает на них
+func report(conn net.Conn, source monitor.ForegroundSource, interval time.Duration) {
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for ; ; <-ticker.C {
+		msg := models.IPCMessage{Command: CmdForeground}
+		if w, ok, err := source.Foreground(); err == nil && ok {
+			msg.Program, msg.Title, msg.PID = w.App, w.Title, w.PID
+		}
+		if err := encoder.Encode(msg); err != nil {
+			return
+		}
+		var ack models.IPCMessage
+		if err := decoder.Decode(&ack); err != nil {
+			return
+		}
+	}
+}
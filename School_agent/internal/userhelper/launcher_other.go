@@ -0,0 +1,16 @@
+//go:build !windows
+
+package userhelper
+
+import "time"
+
+// Launcher вне Windows ничего не запускает: сессий рабочего стола нет
+type Launcher struct{}
+
+func NewLauncher(interval time.Duration) *Launcher {
+	return &Launcher{}
+}
+
+func (l *Launcher) Ensure() {}
+
+func (l *Launcher) Stop() {}
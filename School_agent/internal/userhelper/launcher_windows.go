@@ -0,0 +1,112 @@
+package userhelper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Нет активной консольной сессии
+const noSession = 0xFFFFFFFF
+
+// Launcher держит помощник запущенным в активной консольной сессии:
+// перезапускает его, если он завершился или сменилась сессия.
+type Launcher struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	session uint32
+	proc    windows.Handle
+}
+
+func NewLauncher(interval time.Duration) *Launcher {
+	return &Launcher{interval: interval}
+}
+
+func (l *Launcher) Ensure() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	session := windows.WTSGetActiveConsoleSessionId()
+	if session == noSession {
+		l.stop()
+		return
+	}
+	if l.proc != 0 && l.session == session && running(l.proc) {
+		return
+	}
+	l.stop()
+
+	proc, err := l.start(session)
+	if err != nil {
+		log.Printf("Cannot start foreground helper in session %d: %v", session, err)
+		return
+	}
+	l.proc, l.session = proc, session
+	log.Printf("Foreground helper started in session %d", session)
+}
+
+func (l *Launcher) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stop()
+}
+
+func (l *Launcher) stop() {
+	if l.proc == 0 {
+		return
+	}
+	windows.TerminateProcess(l.proc, 0)
+	windows.CloseHandle(l.proc)
+	l.proc = 0
+}
+
+// start запускает копию агента от имени пользователя сессии на его рабочем столе
+func (l *Launcher) start(session uint32) (windows.Handle, error) {
+	var token windows.Token
+	if err := windows.WTSQueryUserToken(session, &token); err != nil {
+		return 0, fmt.Errorf("WTSQueryUserToken: %w", err)
+	}
+	defer token.Close()
+
+	var env *uint16
+	if err := windows.CreateEnvironmentBlock(&env, token, false); err != nil {
+		return 0, fmt.Errorf("CreateEnvironmentBlock: %w", err)
+	}
+	defer windows.DestroyEnvironmentBlock(env)
+
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	cmdline, err := windows.UTF16PtrFromString(fmt.Sprintf(`"%s" %s %d`, exe, Arg, int(l.interval.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	desktop, _ := windows.UTF16PtrFromString(`winsta0\default`)
+
+	si := windows.StartupInfo{
+		Desktop:    desktop,
+		Flags:      windows.STARTF_USESHOWWINDOW,
+		ShowWindow: windows.SW_HIDE,
+	}
+	si.Cb = uint32(unsafe.Sizeof(si))
+	var pi windows.ProcessInformation
+
+	flags := uint32(windows.CREATE_UNICODE_ENVIRONMENT | windows.CREATE_NO_WINDOW)
+	if err := windows.CreateProcessAsUser(token, nil, cmdline, nil, nil, false, flags, env, nil, &si, &pi); err != nil {
+		return 0, fmt.Errorf("CreateProcessAsUser: %w", err)
+	}
+	windows.CloseHandle(pi.Thread)
+	return pi.Process, nil
+}
+
+func running(proc windows.Handle) bool {
+	event, err := windows.WaitForSingleObject(proc, 0)
+	return err == nil && event == uint32(windows.WAIT_TIMEOUT)
+}
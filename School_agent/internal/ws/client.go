@@ -1,31 +1,62 @@
 package ws
 
 import (
+	"errors"
 	"log"
+	"school_agent/internal/config"
 	"school_agent/internal/models"
+	"school_agent/internal/netproxy"
+	"school_agent/internal/protocol"
 	"sync"
 	"time"
+)
 
-	"github.com/gorilla/websocket"
+const (
+	// После стольких неудачных WebSocket upgrade подряд переходим на HTTPS
+	wsFailuresBeforeFallback = 3
+	// Как долго работаем через HTTPS, прежде чем снова попробовать WebSocket
+	fallbackRetryWS = 30 * time.Minute
 )
 
+// Команда сервера с выбранной версией протокола, обрабатывается внутри клиента
+const cmdProtocol = "PROTOCOL"
+
 type Client struct {
-	url      string
-	token    string
 	hostname string
-	conn     *websocket.Conn
-	mu       sync.Mutex
-	
+	token    string
+	mode     string
+
+	ws   Transport
+	http Transport
+
+	mu        sync.Mutex
+	transport Transport
+	encoder   protocol.Encoder
+	caps      map[string]bool
+
+	wsFailures    int
+	fallbackUntil time.Time
 
 	CommandChan chan models.WSCommand
+	// Сигнал о каждом новом соединении: можно отправить накопленное офлайн
+	ConnectedChan chan struct{}
 }
 
-func New(url, token, hostname string) *Client {
+func New(cfg *config.Config) *Client {
+	httpBase := cfg.HTTPFallbackURL
+	if httpBase == "" {
+		httpBase = httpBaseFromWS(cfg.ServerURL)
+	}
+
 	return &Client{
-		url:         url,
-		token:       token,
-		hostname:    hostname,
-		CommandChan: make(chan models.WSCommand, 10),
+		hostname:      cfg.Hostname,
+		token:         cfg.DeviceToken,
+		mode:          cfg.Transport,
+		encoder:       protocol.For(protocol.V1),
+		ws:            newWSTransport(cfg.ServerURL, netproxy.Func(cfg.Proxy), cfg.Upload.Compression == config.CompressionDeflate),
+		http:          newHTTPTransport(httpBase, cfg.DeviceToken, cfg.Hostname, netproxy.HTTPClient(cfg.Proxy, httpPollTimeout+15*time.Second)),
+		CommandChan:   make(chan models.WSCommand, 10),
+		ConnectedChan: make(chan struct{}, 1),
 	}
 }
 
@@ -39,51 +70,130 @@ func (c *Client) connectLoop(stopChan chan struct{}) {
 		case <-stopChan:
 			return
 		default:
-			conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
-			if err != nil {
+			// До ответа сервера говорим на V1 — его понимают все версии сервера
+			c.setProtocol(protocol.V1, nil)
+
+			t := c.nextTransport()
+			if err := t.Connect(protocol.Auth(c.token, c.hostname)); err != nil {
+				c.connectFailed(t, err)
+				t.Close()
 				time.Sleep(10 * time.Second)
 				continue
 			}
+			c.connected(t)
 
 			c.mu.Lock()
-			c.conn = conn
+			c.transport = t
 			c.mu.Unlock()
 
-			c.SendJSON(map[string]string{"type": "auth", "token": c.token})
-			
-			log.Println("WS Connected")
+			log.Printf("Connected to server via %s", t.Name())
+			select {
+			case c.ConnectedChan <- struct{}{}:
+			default:
+			}
 
 			for {
-				var cmd models.WSCommand
-				err := conn.ReadJSON(&cmd)
+				cmd, err := t.Receive()
 				if err != nil {
-					break 
+					break
+				}
+				if cmd.Type == cmdProtocol {
+					c.setProtocol(cmd.Version, cmd.Capabilities)
+					continue
 				}
 				c.CommandChan <- cmd
 			}
-			
+
 			c.mu.Lock()
-			c.conn = nil
+			c.transport = nil
 			c.mu.Unlock()
+			t.Close()
 		}
 	}
 }
 
-func (c *Client) SendHeartbeat(user string) {
-	payload := map[string]interface{}{
-		"type":      "heartbeat",
-		"device":    c.hostname,
-		"user":      user,
-		"timestamp": time.Now(),
+// nextTransport выбирает транспорт для следующей попытки подключения
+func (c *Client) nextTransport() Transport {
+	switch c.mode {
+	case config.TransportWebSocket:
+		return c.ws
+	case config.TransportHTTPS:
+		return c.http
+	}
+
+	if time.Now().Before(c.fallbackUntil) {
+		return c.http
 	}
-	c.SendJSON(payload)
+	return c.ws
 }
 
-func (c *Client) SendJSON(v interface{}) error {
+// connectFailed считает только отказы в upgrade: сетевые ошибки и
+// недоступный сервер так же помешали бы и HTTPS
+func (c *Client) connectFailed(t Transport, err error) {
+	if t != c.ws || c.mode == config.TransportWebSocket || !errors.Is(err, ErrUpgradeRejected) {
+		return
+	}
+
+	c.wsFailures++
+	if c.wsFailures >= wsFailuresBeforeFallback {
+		log.Printf("WebSocket upgrade failed %d times (%v), falling back to HTTPS", c.wsFailures, err)
+		c.wsFailures = 0
+		c.fallbackUntil = time.Now().Add(fallbackRetryWS)
+	}
+}
+
+func (c *Client) connected(t Transport) {
+	if t == c.ws {
+		c.wsFailures = 0
+		c.fallbackUntil = time.Time{}
+	}
+}
+
+func (c *Client) setProtocol(version int, caps []string) {
+	if !protocol.IsSupported(version) {
+		log.Printf("Server chose unsupported protocol version %d, staying on V1", version)
+		version = protocol.V1
+	}
+	// Возможности согласованы для версии сервера; в V1 их нет
+	if version == protocol.V1 {
+		caps = nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.encoder = protocol.ForCapabilities(version, caps)
+	c.caps = make(map[string]bool)
+	for _, cp := range caps {
+		c.caps[cp] = true
+	}
+}
+
+// Encoder возвращает энкодер текущей согласованной версии протокола
+func (c *Client) Encoder() protocol.Encoder {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.encoder
+}
+
+// HasCapability сообщает, подтвердил ли сервер возможность
+func (c *Client) HasCapability(capability string) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if c.conn == nil {
-		return nil
+	return c.caps[capability]
+}
+
+func (c *Client) SendHeartbeat(user string) {
+	c.SendJSON(c.Encoder().Heartbeat(c.hostname, user, time.Now()))
+}
+
+// SendJSON отправляет сообщение через текущий транспорт.
+// Без соединения возвращает ErrNotConnected.
+func (c *Client) SendJSON(v interface{}) error {
+	c.mu.Lock()
+	t := c.transport
+	c.mu.Unlock()
+	if t == nil {
+		return ErrNotConnected
 	}
-	return c.conn.WriteJSON(v)
-}
\ No newline at end of file
+	return t.Send(v)
+}
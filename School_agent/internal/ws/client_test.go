@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"school_agent/internal/config"
+	"school_agent/internal/models"
+	"school_agent/internal/protocol"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTransport отдает команды из канала; закрытие канала рвет соединение
+type fakeTransport struct {
+	name       string
+	connectErr error
+	cmds       chan models.WSCommand
+
+	mu   sync.Mutex
+	auth interface{}
+	sent []interface{}
+}
+
+func newFakeTransport(name string) *fakeTransport {
+	return &fakeTransport{name: name, cmds: make(chan models.WSCommand, 10)}
+}
+
+func (f *fakeTransport) Name() string { return f.name }
+
+func (f *fakeTransport) Connect(auth interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = auth
+	return f.connectErr
+}
+
+func (f *fakeTransport) Send(v interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, v)
+	return nil
+}
+
+func (f *fakeTransport) Receive() (models.WSCommand, error) {
+	cmd, ok := <-f.cmds
+	if !ok {
+		return cmd, errors.New("closed")
+	}
+	return cmd, nil
+}
+
+func (f *fakeTransport) Close() error { return nil }
+
+func newTestClient(mode string, wsT, httpT Transport) *Client {
+	return &Client{
+		hostname:      "pc-01",
+		token:         "token",
+		mode:          mode,
+		encoder:       protocol.For(protocol.V1),
+		ws:            wsT,
+		http:          httpT,
+		CommandChan:   make(chan models.WSCommand, 10),
+		ConnectedChan: make(chan struct{}, 1),
+	}
+}
+
+func TestSetProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  int
+		caps     []string
+		want     int
+		metadata bool
+		chunks   bool
+	}{
+		{"v2 with all capabilities", protocol.V2, protocol.Capabilities, protocol.V2, true, true},
+		{"v2 without metadata", protocol.V2, []string{protocol.CapLogChunks}, protocol.V2, false, true},
+		{"v1", protocol.V1, nil, protocol.V1, false, false},
+		{"unsupported version", 7, protocol.Capabilities, protocol.V1, false, false},
+		{"v1 with capabilities", protocol.V1, protocol.Capabilities, protocol.V1, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(config.TransportWebSocket, newFakeTransport("websocket"), nil)
+			c.setProtocol(tt.version, tt.caps)
+
+			if v := c.Encoder().Version(); v != tt.want {
+				t.Errorf("encoder version = %d, want %d", v, tt.want)
+			}
+			if got := c.HasCapability(protocol.CapLogChunks); got != tt.chunks {
+				t.Errorf("HasCapability(log_chunks) = %v, want %v", got, tt.chunks)
+			}
+			data, _ := json.Marshal(c.Encoder().Logs("pc-01", []models.LogEntry{{Category: models.CategoryGames}}))
+			if got := strings.Contains(string(data), `"category"`); got != tt.metadata {
+				t.Errorf("logs carry metadata = %v, want %v: %s", got, tt.metadata, data)
+			}
+		})
+	}
+}
+
+func TestProtocolCommandSwitchesEncoder(t *testing.T) {
+	ft := newFakeTransport("websocket")
+	c := newTestClient(config.TransportWebSocket, ft, nil)
+
+	stop := make(chan struct{})
+	defer close(ft.cmds) // после stop: цикл подключения завершится
+	defer close(stop)
+	c.Start(stop)
+
+	ft.cmds <- models.WSCommand{Type: cmdProtocol, Version: protocol.V2, Capabilities: []string{protocol.CapLogChunks}}
+	ft.cmds <- models.WSCommand{Type: "GET_USER"}
+
+	select {
+	case cmd := <-c.CommandChan:
+		if cmd.Type != "GET_USER" {
+			t.Fatalf("got command %q, PROTOCOL must be handled inside the client", cmd.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("command not delivered")
+	}
+
+	if v := c.Encoder().Version(); v != protocol.V2 {
+		t.Errorf("encoder version after PROTOCOL = %d, want 2", v)
+	}
+	if !c.HasCapability(protocol.CapLogChunks) || c.HasCapability(protocol.CapLogMetadata) {
+		t.Errorf("capabilities = %v, want only log_chunks", c.caps)
+	}
+	ft.mu.Lock()
+	auth := ft.auth
+	ft.mu.Unlock()
+	if data, _ := json.Marshal(auth); !strings.Contains(string(data), `"versions":[2,1]`) {
+		t.Errorf("auth = %s, want advertised versions", data)
+	}
+}
+
+func TestSendJSONWithoutTransport(t *testing.T) {
+	c := newTestClient(config.TransportAuto, newFakeTransport("websocket"), newFakeTransport("http"))
+	if err := c.SendJSON(map[string]string{"type": "ping"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("SendJSON offline = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestConnectedSignal(t *testing.T) {
+	ft := newFakeTransport("websocket")
+	c := newTestClient(config.TransportWebSocket, ft, nil)
+
+	stop := make(chan struct{})
+	defer close(ft.cmds)
+	defer close(stop)
+	c.Start(stop)
+
+	select {
+	case <-c.ConnectedChan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no connected signal")
+	}
+	if err := c.SendJSON(map[string]string{"type": "alert"}); err != nil {
+		t.Fatalf("SendJSON after connect: %v", err)
+	}
+}
+
+func TestFallbackCountsOnlyRejectedUpgrades(t *testing.T) {
+	wsT := newFakeTransport("websocket")
+	c := newTestClient(config.TransportAuto, wsT, newFakeTransport("http"))
+
+	// Сеть недоступна: HTTPS не поможет, остаемся на WebSocket
+	for i := 0; i < wsFailuresBeforeFallback; i++ {
+		c.connectFailed(wsT, errors.New("dial tcp: connection refused"))
+	}
+	if c.nextTransport() != wsT {
+		t.Fatal("fell back to HTTPS after network errors")
+	}
+
+	for i := 0; i < wsFailuresBeforeFallback; i++ {
+		c.connectFailed(wsT, fmt.Errorf("%w: HTTP 403", ErrUpgradeRejected))
+	}
+	if c.nextTransport() == wsT {
+		t.Fatal("no fallback after rejected upgrades")
+	}
+}
@@ -0,0 +1,247 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"school_agent/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	httpFlushInterval = 1 * time.Second
+	httpPollTimeout   = 30 * time.Second
+	httpMaxOutbox     = 1000
+)
+
+// httpTransport — запасной транспорт для сетей, где прокси режут WebSocket upgrade.
+// Исходящие сообщения копятся и уходят пачками через POST {base}/messages,
+// команды забираются long-polling запросом GET {base}/commands.
+type httpTransport struct {
+	base     string
+	token    string
+	hostname string
+	client   *http.Client
+
+	mu      sync.Mutex
+	outbox  []interface{}
+	pending []models.WSCommand
+	sendErr error
+	stop    chan struct{}
+}
+
+func newHTTPTransport(base, token, hostname string, client *http.Client) *httpTransport {
+	return &httpTransport{
+		base:     strings.TrimRight(base, "/"),
+		token:    token,
+		hostname: hostname,
+		client:   client,
+	}
+}
+
+// httpBaseFromWS выводит адрес REST API из адреса WebSocket:
+// ws://host/ws -> http://host/agent, wss://host/ws -> https://host/agent
+func httpBaseFromWS(wsURL string) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	u.Path = strings.TrimSuffix(strings.TrimRight(u.Path, "/"), "/ws") + "/agent"
+	return u.String()
+}
+
+func (t *httpTransport) Name() string {
+	return "https"
+}
+
+func (t *httpTransport) Connect(auth interface{}) error {
+	if t.base == "" {
+		return errors.New("http fallback url is not configured")
+	}
+
+	if err := t.post([]interface{}{auth}); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.sendErr = nil
+	t.stop = make(chan struct{})
+	stop := t.stop
+	t.mu.Unlock()
+
+	go t.flushLoop(stop)
+	return nil
+}
+
+func (t *httpTransport) Send(v interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop == nil {
+		return ErrNotConnected
+	}
+	if t.sendErr != nil {
+		return t.sendErr
+	}
+	if len(t.outbox) >= httpMaxOutbox {
+		return errors.New("http outbox is full")
+	}
+	t.outbox = append(t.outbox, v)
+	return nil
+}
+
+func (t *httpTransport) Receive() (models.WSCommand, error) {
+	for {
+		t.mu.Lock()
+		if t.stop == nil {
+			t.mu.Unlock()
+			return models.WSCommand{}, ErrNotConnected
+		}
+		if t.sendErr != nil {
+			err := t.sendErr
+			t.mu.Unlock()
+			return models.WSCommand{}, err
+		}
+		if len(t.pending) > 0 {
+			cmd := t.pending[0]
+			t.pending = t.pending[1:]
+			t.mu.Unlock()
+			return cmd, nil
+		}
+		t.mu.Unlock()
+
+		cmds, err := t.poll()
+		if err != nil {
+			return models.WSCommand{}, err
+		}
+
+		t.mu.Lock()
+		t.pending = append(t.pending, cmds...)
+		t.mu.Unlock()
+	}
+}
+
+func (t *httpTransport) Close() error {
+	t.mu.Lock()
+	if t.stop == nil {
+		t.mu.Unlock()
+		return nil
+	}
+	close(t.stop)
+	t.stop = nil
+	batch := t.outbox
+	t.outbox = nil
+	t.pending = nil
+	t.mu.Unlock()
+
+	if len(batch) > 0 {
+		return t.post(batch)
+	}
+	return nil
+}
+
+func (t *httpTransport) flushLoop(stop chan struct{}) {
+	ticker := time.NewTicker(httpFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			t.mu.Lock()
+			batch := t.outbox
+			t.outbox = nil
+			t.mu.Unlock()
+
+			if len(batch) == 0 {
+				continue
+			}
+
+			if err := t.post(batch); err != nil {
+				// Возвращаем пачку в начало очереди, Receive сообщит о разрыве
+				t.mu.Lock()
+				t.outbox = append(batch, t.outbox...)
+				if len(t.outbox) > httpMaxOutbox {
+					t.outbox = t.outbox[:httpMaxOutbox]
+				}
+				t.sendErr = err
+				t.mu.Unlock()
+				return
+			}
+		}
+	}
+}
+
+func (t *httpTransport) post(batch []interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"device":   t.hostname,
+		"messages": batch,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.base+"/messages", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.authorize(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST messages: %s", resp.Status)
+	}
+	return nil
+}
+
+func (t *httpTransport) poll() ([]models.WSCommand, error) {
+	q := url.Values{}
+	q.Set("device", t.hostname)
+	q.Set("wait", fmt.Sprint(int(httpPollTimeout.Seconds())))
+
+	req, err := http.NewRequest(http.MethodGet, t.base+"/commands?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.authorize(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("GET commands: %s", resp.Status)
+	}
+
+	var cmds []models.WSCommand
+	if err := json.NewDecoder(resp.Body).Decode(&cmds); err != nil {
+		return nil, err
+	}
+	return cmds, nil
+}
+
+func (t *httpTransport) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+t.token)
+}
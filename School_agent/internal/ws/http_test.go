@@ -0,0 +1,209 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"school_agent/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// agentAPI — сервер REST API запасного транспорта
+type agentAPI struct {
+	t        *testing.T
+	commands chan []models.WSCommand
+
+	mu       sync.Mutex
+	batches  [][]json.RawMessage
+	auth     []string
+	polls    []string
+	failPost bool
+}
+
+func newAgentAPI(t *testing.T) (*agentAPI, *httptest.Server) {
+	api := &agentAPI{t: t, commands: make(chan []models.WSCommand, 10)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/agent/messages", api.messages)
+	mux.HandleFunc("/agent/commands", api.poll)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return api, srv
+}
+
+func (a *agentAPI) messages(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Device   string            `json:"device"`
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil || body.Device != "pc-01" {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.auth = append(a.auth, r.Header.Get("Authorization"))
+	if a.failPost {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	a.batches = append(a.batches, body.Messages)
+}
+
+// poll — long-polling: ждет команды, пустой ответ через 200 мс
+func (a *agentAPI) poll(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.polls = append(a.polls, r.URL.RawQuery)
+	a.mu.Unlock()
+	select {
+	case cmds := <-a.commands:
+		json.NewEncoder(w).Encode(cmds)
+	case <-time.After(200 * time.Millisecond):
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+// messageTypes — типы всех принятых сообщений по порядку
+func (a *agentAPI) messageTypes() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var types []string
+	for _, batch := range a.batches {
+		for _, m := range batch {
+			var msg struct {
+				Type string `json:"type"`
+			}
+			json.Unmarshal(m, &msg)
+			types = append(types, msg.Type)
+		}
+	}
+	return types
+}
+
+func newTestHTTPTransport(srv *httptest.Server) *httpTransport {
+	return newHTTPTransport(srv.URL+"/agent/", "secret", "pc-01", srv.Client())
+}
+
+func TestHTTPTransportAuth(t *testing.T) {
+	api, srv := newAgentAPI(t)
+	tr := newTestHTTPTransport(srv)
+	if err := tr.Connect(map[string]string{"type": "auth"}); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer tr.Close()
+
+	if got := api.messageTypes(); len(got) != 1 || got[0] != "auth" {
+		t.Fatalf("messages after Connect: %v", got)
+	}
+	api.mu.Lock()
+	auth := api.auth[0]
+	api.failPost = true
+	api.mu.Unlock()
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	if err := newTestHTTPTransport(srv).Connect(map[string]string{"type": "auth"}); err == nil {
+		t.Error("Connect succeeded when the server rejected auth")
+	}
+}
+
+func TestHTTPTransportReceive(t *testing.T) {
+	api, srv := newAgentAPI(t)
+	tr := newTestHTTPTransport(srv)
+	if err := tr.Connect(map[string]string{"type": "auth"}); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	go func() {
+		// Первый опрос уходит пустым, команды приходят следующим
+		time.Sleep(300 * time.Millisecond)
+		api.commands <- []models.WSCommand{{Type: "GET_USER"}, {Type: "UPLOAD_LOGS"}}
+	}()
+	for _, want := range []string{"GET_USER", "UPLOAD_LOGS"} {
+		cmd, err := tr.Receive()
+		if err != nil || cmd.Type != want {
+			t.Fatalf("Receive = %+v, %v; want %s", cmd, err, want)
+		}
+	}
+
+	api.mu.Lock()
+	query := api.polls[0]
+	api.mu.Unlock()
+	if query != "device=pc-01&wait=30" {
+		t.Errorf("poll query = %q", query)
+	}
+}
+
+func TestHTTPTransportSendAndClose(t *testing.T) {
+	api, srv := newAgentAPI(t)
+	tr := newTestHTTPTransport(srv)
+	if err := tr.Send(map[string]string{"type": "heartbeat"}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Send before Connect = %v, want ErrNotConnected", err)
+	}
+	if err := tr.Connect(map[string]string{"type": "auth"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Пачка уходит раз в httpFlushInterval
+	tr.Send(map[string]string{"type": "heartbeat"})
+	deadline := time.Now().Add(3 * httpFlushInterval)
+	for len(api.messageTypes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if got := api.messageTypes(); len(got) != 2 || got[1] != "heartbeat" {
+		t.Fatalf("messages after flush: %v", got)
+	}
+
+	// Close отправляет остаток очереди сразу
+	tr.Send(map[string]string{"type": "alert"})
+	if err := tr.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := api.messageTypes(); len(got) != 3 || got[2] != "alert" {
+		t.Fatalf("messages after Close: %v", got)
+	}
+	if err := tr.Send(map[string]string{"type": "heartbeat"}); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Send after Close = %v, want ErrNotConnected", err)
+	}
+	if _, err := tr.Receive(); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Receive after Close = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestHTTPTransportSendFailureBreaksReceive(t *testing.T) {
+	api, srv := newAgentAPI(t)
+	tr := newTestHTTPTransport(srv)
+	if err := tr.Connect(map[string]string{"type": "auth"}); err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	api.mu.Lock()
+	api.failPost = true
+	api.mu.Unlock()
+	tr.Send(map[string]string{"type": "heartbeat"})
+
+	// Неудачная отправка рвет соединение: клиент переподключится
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := tr.Receive(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	select {
+	case err := <-done:
+		if errors.Is(err, ErrNotConnected) {
+			t.Errorf("Receive error = %v, want the POST failure", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Receive did not report the failed POST")
+	}
+}
@@ -0,0 +1,24 @@
+package ws
+
+import (
+	"errors"
+	"school_agent/internal/models"
+)
+
+// ErrNotConnected возвращается при отправке без активного соединения
+var ErrNotConnected = errors.New("transport not connected")
+
+// ErrUpgradeRejected — сервер или прокси принял соединение, но WebSocket
+// через него не открылся
+var ErrUpgradeRejected = errors.New("websocket upgrade rejected")
+
+// Transport — канал связи агента с сервером (WebSocket или HTTPS long-polling).
+// Семантика одинаковая: Connect подключается и отправляет сообщение auth,
+// Send отправляет одно JSON-сообщение, Receive блокируется до следующей команды сервера.
+type Transport interface {
+	Name() string
+	Connect(auth interface{}) error
+	Send(v interface{}) error
+	Receive() (models.WSCommand, error)
+	Close() error
+}
@@ -0,0 +1,110 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"school_agent/internal/models"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type wsTransport struct {
+	url    string
+	dialer *websocket.Dialer
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func newWSTransport(url string, proxy func(*http.Request) (*url.URL, error), compress bool) *wsTransport {
+	return &wsTransport{
+		url: url,
+		dialer: &websocket.Dialer{
+			Proxy:             proxy,
+			HandshakeTimeout:  45 * time.Second,
+			EnableCompression: compress,
+		},
+	}
+}
+
+func (t *wsTransport) Name() string {
+	return "websocket"
+}
+
+// Connect подключается к серверу. Ошибка после того, как TCP-соединение
+// с сервером или прокси уже установлено (прокси отказал в CONNECT, оборвал
+// соединение, ответил не 101), считается отказом в upgrade: с ней поможет
+// HTTPS. Ошибки DNS и TCP — нет, сервер недоступен для любого транспорта.
+func (t *wsTransport) Connect(auth interface{}) error {
+	var reached atomic.Bool
+	dialer := *t.dialer
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err == nil {
+			reached.Store(true)
+		}
+		return conn, err
+	}
+
+	conn, resp, err := dialer.Dial(t.url, nil)
+	if err != nil {
+		if reached.Load() || resp != nil {
+			return fmt.Errorf("%w: %v", ErrUpgradeRejected, handshakeStatus(resp, err))
+		}
+		return err
+	}
+
+	t.mu.Lock()
+	t.conn = conn
+	t.mu.Unlock()
+
+	return t.Send(auth)
+}
+
+// handshakeStatus дополняет ошибку кодом ответа, если он есть
+func handshakeStatus(resp *http.Response, err error) error {
+	if resp == nil {
+		return err
+	}
+	return fmt.Errorf("HTTP %s: %v", resp.Status, err)
+}
+
+func (t *wsTransport) Send(v interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return ErrNotConnected
+	}
+	return t.conn.WriteJSON(v)
+}
+
+func (t *wsTransport) Receive() (models.WSCommand, error) {
+	var cmd models.WSCommand
+
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return cmd, ErrNotConnected
+	}
+
+	err := conn.ReadJSON(&cmd)
+	return cmd, err
+}
+
+func (t *wsTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
@@ -0,0 +1,82 @@
+package ws
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func proxyTo(addr string) func(*http.Request) (*url.URL, error) {
+	return func(*http.Request) (*url.URL, error) {
+		return &url.URL{Scheme: "http", Host: addr}, nil
+	}
+}
+
+func TestWSTransportRejectedUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	tr := newWSTransport("ws"+strings.TrimPrefix(srv.URL, "http"), nil, false)
+	if err := tr.Connect(nil); !errors.Is(err, ErrUpgradeRejected) {
+		t.Fatalf("Connect to non-WebSocket endpoint = %v, want ErrUpgradeRejected", err)
+	}
+
+	addr := srv.Listener.Addr().String()
+	srv.Close()
+	if err := newWSTransport("ws://"+addr, nil, false).Connect(nil); err == nil || errors.Is(err, ErrUpgradeRejected) {
+		t.Fatalf("Connect to closed port = %v, want a dial error", err)
+	}
+}
+
+func TestWSTransportProxyRefusesConnect(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			t.Errorf("proxy got %s, want CONNECT", r.Method)
+		}
+		http.Error(w, "websocket is not allowed", http.StatusForbidden)
+	}))
+	defer proxy.Close()
+
+	tr := newWSTransport("wss://server.school.example/ws", proxyTo(proxy.Listener.Addr().String()), false)
+	if err := tr.Connect(nil); !errors.Is(err, ErrUpgradeRejected) {
+		t.Fatalf("Connect through refusing proxy = %v, want ErrUpgradeRejected", err)
+	}
+}
+
+func TestWSTransportProxyDropsHandshake(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			// Прочитать запрос и оборвать соединение, как делают фильтрующие прокси
+			conn.Read(make([]byte, 4096))
+			conn.Close()
+		}
+	}()
+
+	tr := newWSTransport("ws://"+ln.Addr().String()+"/ws", nil, false)
+	if err := tr.Connect(nil); !errors.Is(err, ErrUpgradeRejected) {
+		t.Fatalf("Connect with dropped handshake = %v, want ErrUpgradeRejected", err)
+	}
+
+	// Прокси недоступен — это не отказ в upgrade
+	addr := ln.Addr().String()
+	ln.Close()
+	tr = newWSTransport("ws://server.school.example/ws", proxyTo(addr), false)
+	if err := tr.Connect(nil); err == nil || errors.Is(err, ErrUpgradeRejected) {
+		t.Fatalf("Connect through unreachable proxy = %v, want a dial error", err)
+	}
+}